@@ -6,15 +6,17 @@ import (
 	"github.com/elliotchance/pie/v2"
 	"github.com/go-resty/resty/v2"
 	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/anyx"
 	"github.com/ice-cream-heaven/utils/json"
 	"github.com/ice-cream-heaven/vanilla/dns"
 	"github.com/metacubex/mihomo/adapter/outbound"
 	"github.com/metacubex/mihomo/constant"
 	"golang.org/x/exp/maps"
-	"io"
 	"net"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -35,6 +37,14 @@ type Adapter struct {
 	uniqueId    string
 	vanillaLink string
 
+	// 重命名后的名称，为空时使用 ProxyAdapter 的名称
+	name string
+
+	// 原 clash 配置中包含该节点的策略组名称
+	groups []string
+
+	history *URLTestHistory
+
 	client *resty.Client
 
 	// 一些特殊配置
@@ -45,12 +55,7 @@ type Adapter struct {
 func NewAdapter(c constant.ProxyAdapter, o any) (*Adapter, error) {
 	p := &Adapter{
 		ProxyAdapter: c,
-		client: resty.New().
-			SetTimeout(time.Minute * 10).
-			SetRetryWaitTime(time.Second).
-			SetRetryCount(3).
-			SetLogger(log.Clone().SetOutput(io.Discard)).
-			SetRedirectPolicy(resty.FlexibleRedirectPolicy(10)),
+		history:      newURLTestHistory(DefaultURLTestHistorySize),
 	}
 
 	//switch c.Type() {
@@ -68,16 +73,30 @@ func NewAdapter(c constant.ProxyAdapter, o any) (*Adapter, error) {
 		return nil, err
 	}
 
-	{
-		p.client.JSONUnmarshal = json.Unmarshal
-		p.client.JSONMarshal = json.Marshal
-		p.client.SetTransport(p.Transport()).
-			SetLogger(log.Clone().SetPrefixMsg(fmt.Sprintf("vanilla[%s]", p.ShortId())))
-	}
+	p.initClient()
 
 	return p, nil
 }
 
+// initClient 需要在 uniqueId 确定后调用，日志前缀中包含 ShortId
+func (p *Adapter) initClient() {
+	p.client = newRestyClient(p.Transport(), fmt.Sprintf("vanilla[%s]", p.ShortId()))
+}
+
+// newRestyClient 节点与策略组共用的 http 客户端
+func newRestyClient(transport http.RoundTripper, logPrefix string) *resty.Client {
+	client := resty.New().
+		SetTimeout(time.Minute * 10).
+		SetRetryWaitTime(time.Second).
+		SetRetryCount(3).
+		SetRedirectPolicy(resty.FlexibleRedirectPolicy(10)).
+		SetTransport(transport).
+		SetLogger(log.Clone().SetPrefixMsg(logPrefix))
+	client.JSONUnmarshal = json.Unmarshal
+	client.JSONMarshal = json.Marshal
+	return client
+}
+
 //func (p *Adapter) validateAddr() error {
 //	host, _, err := net.SplitHostPort(p.Addr())
 //	if err != nil {
@@ -108,6 +127,25 @@ func NewAdapter(c constant.ProxyAdapter, o any) (*Adapter, error) {
 //	return nil
 //}
 
+func (p *Adapter) Name() string {
+	if p.name != "" {
+		return p.name
+	}
+	return p.ProxyAdapter.Name()
+}
+
+// WithName 返回使用新名称的浅拷贝，不影响原节点，unique_id 与名称无关保持不变
+func (p *Adapter) WithName(name string) *Adapter {
+	c := *p
+	c.name = name
+	return &c
+}
+
+// Groups 节点在原 clash 配置中所属的 proxy-groups，用于 ToClashProfile 重新导出
+func (p *Adapter) Groups() []string {
+	return p.groups
+}
+
 func (p *Adapter) Hostname() string {
 	host, _, _ := net.SplitHostPort(p.Addr())
 	return host
@@ -150,599 +188,638 @@ func (p *Adapter) ToV2ray() string {
 			return ""
 		}
 
+		// https://shadowsocks.org/doc/sip002.html
 		u := &url.URL{
 			Scheme: "ss",
 			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
 		}
 
-		user := url.UserPassword(opt.Cipher, opt.Password)
-		u.User = url.User(base64.StdEncoding.EncodeToString([]byte(user.String())))
+		// https://shadowsocks.org/doc/sip022.html
+		if strings.HasPrefix(opt.Cipher, "2022-") {
+			u.User = url.UserPassword(opt.Cipher, opt.Password)
+		} else {
+			u.User = url.User(base64.RawURLEncoding.EncodeToString([]byte(opt.Cipher + ":" + opt.Password)))
+		}
+
+		if opt.Plugin != "" {
+			if plugin := encodePlugin(opt.Plugin, opt.PluginOpts); plugin != "" {
+				u.Path = "/"
+				u.RawQuery = url.Values{"plugin": {plugin}}.Encode()
+			}
+		}
+
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.ShadowsocksR:
+		var opt *outbound.ShadowSocksROption
+		switch x := p.clashOpt.(type) {
+		case outbound.ShadowSocksROption:
+			opt = &x
+		case *outbound.ShadowSocksROption:
+			opt = x
+		default:
+			return ""
+		}
+
+		// ssr://base64(server:port:protocol:method:obfs:base64(password)/?obfsparam=base64&protoparam=base64&remarks=base64)
+		query := url.Values{}
+
+		if opt.ObfsParam != "" {
+			query.Set("obfsparam", base64.RawURLEncoding.EncodeToString([]byte(opt.ObfsParam)))
+		}
+
+		if opt.ProtocolParam != "" {
+			query.Set("protoparam", base64.RawURLEncoding.EncodeToString([]byte(opt.ProtocolParam)))
+		}
+
+		query.Set("remarks", base64.RawURLEncoding.EncodeToString([]byte(p.Name())))
+
+		raw := strings.Join([]string{
+			opt.Server,
+			strconv.Itoa(opt.Port),
+			opt.Protocol,
+			opt.Cipher,
+			opt.Obfs,
+			base64.RawURLEncoding.EncodeToString([]byte(opt.Password)),
+		}, ":") + "/?" + query.Encode()
+
+		return "ssr://" + base64.RawURLEncoding.EncodeToString([]byte(raw))
+
+	case constant.Snell:
+		var opt *outbound.SnellOption
+		switch x := p.clashOpt.(type) {
+		case outbound.SnellOption:
+			opt = &x
+		case *outbound.SnellOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		u := &url.URL{
+			Scheme: "snell",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+			User:   url.User(opt.Psk),
+		}
+
+		query := u.Query()
+
+		if opt.Version != 0 {
+			query.Set("version", strconv.Itoa(opt.Version))
+		}
+
+		if len(opt.ObfsOpts) > 0 {
+			if value, ok := opt.ObfsOpts["mode"]; ok {
+				query.Set("obfs", anyx.ToString(value))
+			}
+
+			if value, ok := opt.ObfsOpts["host"]; ok {
+				query.Set("obfs-host", anyx.ToString(value))
+			}
+		}
+
+		u.RawQuery = query.Encode()
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.Socks5:
+		var opt *outbound.Socks5Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Socks5Option:
+			opt = &x
+		case *outbound.Socks5Option:
+			opt = x
+		default:
+			return ""
+		}
+
+		u := &url.URL{
+			Scheme: "socks5",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+		}
+
+		if opt.UserName != "" || opt.Password != "" {
+			u.User = url.UserPassword(opt.UserName, opt.Password)
+		}
+
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.Http:
+		var opt *outbound.HttpOption
+		switch x := p.clashOpt.(type) {
+		case outbound.HttpOption:
+			opt = &x
+		case *outbound.HttpOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		u := &url.URL{
+			Scheme: "http",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+		}
+
+		if opt.TLS {
+			u.Scheme = "https"
+		}
+
+		if opt.UserName != "" || opt.Password != "" {
+			u.User = url.UserPassword(opt.UserName, opt.Password)
+		}
+
+		if opt.SkipCertVerify {
+			u.RawQuery = url.Values{"allowInsecure": {"1"}}.Encode()
+		}
+
 		u.Fragment = p.Name()
 
 		return u.String()
 
-		//case constant.ShadowsocksR:
-		//	var opt *outbound.ShadowSocksROption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.ShadowSocksROption:
-		//		opt = &x
-		//	case *outbound.ShadowSocksROption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-
-		//case constant.Snell:
-		//	var opt *outbound.SnellOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.SnellOption:
-		//		opt = &x
-		//	case *outbound.SnellOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "snell",
-		//	}
-		//
-		//	query := u.Query()
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.Psk != "" {
-		//		u.User = url.User(opt.Psk)
-		//	}
-		//
-		//	if opt.Version != 0 {
-		//		query.Set("version", strconv.Itoa(opt.Version))
-		//	}
-		//
-		//	if len(opt.ObfsOpts) > 0 {
-		//		for key, value := range opt.ObfsOpts {
-		//			query.Set("obfs-"+key, anyx.ToString(value))
-		//		}
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.Socks5:
-		//	var opt *outbound.Socks5Option
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.Socks5Option:
-		//		opt = &x
-		//	case *outbound.Socks5Option:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "socks5",
-		//	}
-		//
-		//	query := u.Query()
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.UserName != "" && opt.Password != "" {
-		//		u.User = url.UserPassword(opt.UserName, opt.Password)
-		//	} else if opt.UserName != "" {
-		//		u.User = url.User(opt.UserName)
-		//	} else if opt.Password != "" {
-		//		u.User = url.UserPassword("", opt.Password)
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.Http:
-		//	var opt *outbound.HttpOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.HttpOption:
-		//		opt = &x
-		//	case *outbound.HttpOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "http",
-		//	}
-		//
-		//	query := u.Query()
-		//
-		//	if opt.TLS {
-		//		u.Scheme = "https"
-		//	}
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.UserName != "" && opt.Password != "" {
-		//		u.User = url.UserPassword(opt.UserName, opt.Password)
-		//	} else if opt.UserName != "" {
-		//		u.User = url.User(opt.UserName)
-		//	} else if opt.Password != "" {
-		//		u.User = url.UserPassword("", opt.Password)
-		//	}
-		//
-		//	if opt.SNI != "" {
-		//		query.Set("sni", opt.SNI)
-		//	}
-		//
-		//	if len(opt.Headers) > 0 {
-		//		for key, value := range opt.Headers {
-		//			query.Set("header-"+key, value)
-		//		}
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.Vmess:
-		//	var opt *outbound.VmessOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.VmessOption:
-		//		opt = &x
-		//	case *outbound.VmessOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "vmess",
-		//	}
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.UUID != "" {
-		//		u.User = url.User(opt.UUID)
-		//	}
-		//
-		//	query := url.Values{}
-		//
-		//	if opt.ServerName != "" {
-		//		query.Set("sni", opt.ServerName)
-		//	}
-		//
-		//	if len(opt.HTTPOpts.Headers) > 0 {
-		//		if value, ok := opt.HTTPOpts.Headers["Host"]; ok && len(value) > 0 {
-		//			query.Set("http-host", strings.Join(pie.Sort(value), ","))
-		//		}
-		//	}
-		//
-		//	if len(opt.HTTPOpts.Path) > 0 {
-		//		query.Set("http-path", strings.Join(pie.Sort(opt.HTTPOpts.Path), ","))
-		//	}
-		//
-		//	if len(opt.HTTP2Opts.Host) > 0 {
-		//		query.Set("h2-host", strings.Join(pie.Sort(opt.HTTP2Opts.Host), ","))
-		//	}
-		//
-		//	if opt.HTTP2Opts.Path != "" {
-		//		query.Set("h2-path", opt.HTTP2Opts.Path)
-		//	}
-		//
-		//	if len(opt.WSOpts.Headers) > 0 {
-		//		if value, ok := opt.WSOpts.Headers["Host"]; ok && value != "" {
-		//			query.Set("ws-host", value)
-		//		}
-		//	}
-		//
-		//	if opt.WSOpts.Path != "" {
-		//		query.Set("ws-path", opt.WSOpts.Path)
-		//	}
-		//
-		//	if opt.GrpcOpts.GrpcServiceName != "" {
-		//		query.Set("grpc-service-name", opt.GrpcOpts.GrpcServiceName)
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.Vless:
-		//	var opt *outbound.VlessOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.VlessOption:
-		//		opt = &x
-		//	case *outbound.VlessOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "vless",
-		//	}
-		//
-		//	query := u.Query()
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.UUID != "" {
-		//		u.User = url.User(opt.UUID)
-		//	}
-		//
-		//	if opt.Flow != "" {
-		//		query.Set("flow", opt.Flow)
-		//	}
-		//
-		//	if len(opt.ALPN) > 0 {
-		//		query.Set("alpn", strings.Join(pie.Sort(opt.ALPN), ";"))
-		//	}
-		//
-		//	if opt.PacketEncoding != "" {
-		//		query.Set("packet", opt.PacketEncoding)
-		//	}
-		//
-		//	if opt.Network != "" {
-		//		query.Set("net", opt.Network)
-		//	}
-		//
-		//	if opt.RealityOpts.ShortID != "" {
-		//		query.Set("short-id", opt.RealityOpts.ShortID)
-		//	}
-		//
-		//	if opt.RealityOpts.PublicKey != "" {
-		//		query.Set("public-key", opt.RealityOpts.PublicKey)
-		//	}
-		//
-		//	if opt.HTTPOpts.Method != "" {
-		//		query.Set("http-method", opt.HTTPOpts.Method)
-		//	}
-		//
-		//	if len(opt.HTTPOpts.Path) > 0 {
-		//		query.Set("http-path", strings.Join(pie.Sort(opt.HTTPOpts.Path), ","))
-		//	}
-		//
-		//	if len(opt.HTTPOpts.Headers) > 0 {
-		//		if value, ok := opt.HTTPOpts.Headers["Host"]; ok && len(value) > 0 {
-		//			query.Set("http-host", strings.Join(pie.Sort(value), ","))
-		//		}
-		//	}
-		//
-		//	if len(opt.HTTP2Opts.Host) > 0 {
-		//		query.Set("h2-host", strings.Join(pie.Sort(opt.HTTP2Opts.Host), ","))
-		//	}
-		//
-		//	if opt.HTTP2Opts.Path != "" {
-		//		query.Set("h2-path", opt.HTTP2Opts.Path)
-		//	}
-		//
-		//	if opt.GrpcOpts.GrpcServiceName != "" {
-		//		query.Set("grpc-service-name", opt.GrpcOpts.GrpcServiceName)
-		//	}
-		//
-		//	if opt.WSOpts.Path != "" {
-		//		query.Set("ws-path", opt.WSOpts.Path)
-		//	}
-		//
-		//	if len(opt.WSOpts.Headers) > 0 {
-		//		if value, ok := opt.WSOpts.Headers["Host"]; ok && value != "" {
-		//			query.Set("ws-host", value)
-		//		}
-		//	}
-		//
-		//	if opt.WSOpts.V2rayHttpUpgradeFastOpen {
-		//		query.Set("v2ray-http-upgrade-fast-open", "true")
-		//	}
-		//
-		//	if opt.WSOpts.EarlyDataHeaderName != "" {
-		//		query.Set("early-data-header-name", opt.WSOpts.EarlyDataHeaderName)
-		//	}
-		//
-		//	if opt.WSOpts.V2rayHttpUpgrade {
-		//		query.Set("v2ray-http-upgrade", "true")
-		//	}
-		//
-		//	if opt.WSPath != "" {
-		//		query.Set("ws-path", opt.WSPath)
-		//	}
-		//
-		//	if len(opt.WSHeaders) > 0 {
-		//		if value, ok := opt.WSHeaders["Host"]; ok && value != "" {
-		//			query.Set("ws-host", value)
-		//		}
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.Trojan:
-		//	var opt *outbound.TrojanOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.TrojanOption:
-		//		opt = &x
-		//	case *outbound.TrojanOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "trojan",
-		//	}
-		//	query := u.Query()
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.Password != "" {
-		//		u.User = url.User(opt.Password)
-		//	}
-		//
-		//	if opt.Network != "" {
-		//		query.Set("net", opt.Network)
-		//	}
-		//
-		//	if opt.GrpcOpts.GrpcServiceName != "" {
-		//		query.Set("grpc-service-name", opt.GrpcOpts.GrpcServiceName)
-		//	}
-		//
-		//	if len(opt.WSOpts.Headers) > 0 {
-		//		if value, ok := opt.WSOpts.Headers["Host"]; ok && value != "" {
-		//			query.Set("ws-host", value)
-		//		}
-		//	}
-		//
-		//	if opt.WSOpts.Path != "" {
-		//		query.Set("ws-path", opt.WSOpts.Path)
-		//	}
-		//
-		//	if opt.RealityOpts.ShortID != "" {
-		//		query.Set("short-id", opt.RealityOpts.ShortID)
-		//	}
-		//
-		//	if opt.RealityOpts.PublicKey != "" {
-		//		query.Set("public-key", opt.RealityOpts.PublicKey)
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.Hysteria:
-		//	var opt *outbound.HysteriaOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.HysteriaOption:
-		//		opt = &x
-		//	case *outbound.HysteriaOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "hysteria",
-		//	}
-		//
-		//	query := u.Query()
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.AuthString != "" {
-		//		u.User = url.User(opt.AuthString)
-		//	}
-		//
-		//	if opt.Protocol != "" {
-		//		query.Set("protocol", opt.Protocol)
-		//	}
-		//
-		//	if opt.ObfsProtocol != "" {
-		//		query.Set("obfs-protocol", opt.ObfsProtocol)
-		//	}
-		//
-		//	if opt.Obfs != "" {
-		//		query.Set("obfs", opt.Obfs)
-		//	}
-		//
-		//	if opt.SNI != "" {
-		//		query.Set("sni", opt.SNI)
-		//	}
-		//
-		//	if len(opt.ALPN) > 0 {
-		//		query.Set("alpn", strings.Join(pie.Sort(opt.ALPN), ";"))
-		//	}
-		//
-		//	if opt.CustomCAString != "" {
-		//		query.Set("ca", opt.CustomCAString)
-		//	}
-		//
-		//	if opt.ReceiveWindowConn != 0 {
-		//		query.Set("recv-window-conn", strconv.Itoa(opt.ReceiveWindowConn))
-		//	}
-		//
-		//	if opt.ReceiveWindow != 0 {
-		//		query.Set("recv-window", strconv.Itoa(opt.ReceiveWindow))
-		//	}
-		//
-		//	if opt.DisableMTUDiscovery {
-		//		query.Set("disable-mtu-discovery", "true")
-		//	}
-		//
-		//	if opt.FastOpen {
-		//		query.Set("fast-open", "true")
-		//	}
-		//
-		//	if opt.HopInterval != 0 {
-		//		query.Set("hop-interval", strconv.Itoa(opt.HopInterval))
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.WireGuard:
-		//	var opt *outbound.WireGuardOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.WireGuardOption:
-		//		opt = &x
-		//	case *outbound.WireGuardOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "wireguard",
-		//	}
-		//
-		//	query := u.Query()
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.PublicKey != "" {
-		//		u.User = url.User(opt.PublicKey)
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
-
-		//case constant.Tuic:
-		//	var opt *outbound.TuicOption
-		//	switch x := p.clashOpt.(type) {
-		//	case outbound.TuicOption:
-		//		opt = &x
-		//	case *outbound.TuicOption:
-		//		opt = x
-		//	default:
-		//		return ""
-		//	}
-		//
-		//	u := &url.URL{
-		//		Scheme: "tuic",
-		//	}
-		//
-		//	query := u.Query()
-		//
-		//	if opt.Port > 0 {
-		//		u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
-		//	} else {
-		//		u.Host = opt.Server
-		//	}
-		//
-		//	if opt.UUID != "" && opt.Password != "" {
-		//		u.User = url.UserPassword(opt.UUID, opt.Password)
-		//	} else if opt.UUID != "" {
-		//		u.User = url.User(opt.UUID)
-		//	} else if opt.Password != "" {
-		//		u.User = url.UserPassword("", opt.Password)
-		//	}
-		//
-		//	if len(opt.ALPN) > 0 {
-		//		query.Set("alpn", strings.Join(pie.Sort(opt.ALPN), ";"))
-		//	}
-		//
-		//	if opt.ReduceRtt {
-		//		query.Set("reduce-rtt", "true")
-		//	}
-		//
-		//	if opt.UDPOverStream {
-		//		query.Set("udp-over-stream", "true")
-		//	}
-		//
-		//	if opt.UdpRelayMode != "" {
-		//		query.Set("udp-relay-mode", opt.UdpRelayMode)
-		//	}
-		//
-		//	if opt.CongestionController != "" {
-		//		query.Set("congestion-controller", opt.CongestionController)
-		//	}
-		//
-		//	if opt.DisableSni {
-		//		query.Set("disable-sniffing", "true")
-		//	}
-		//
-		//	if opt.FastOpen {
-		//		query.Set("fast-open", "true")
-		//	}
-		//
-		//	if opt.CWND != 0 {
-		//		query.Set("cwnd", strconv.Itoa(opt.CWND))
-		//	}
-		//
-		//	if opt.CustomCAString != "" {
-		//		query.Set("ca", opt.CustomCAString)
-		//	}
-		//
-		//	if opt.SNI != "" {
-		//		query.Set("sni", opt.SNI)
-		//	}
-		//
-		//	if opt.ReceiveWindow != 0 {
-		//		query.Set("recv-window", strconv.Itoa(opt.ReceiveWindow))
-		//	}
-		//
-		//	if opt.ReceiveWindowConn != 0 {
-		//		query.Set("recv-window-conn", strconv.Itoa(opt.ReceiveWindowConn))
-		//	}
-		//
-		//	if opt.UDPOverStream {
-		//		query.Set("udp-over-stream", "true")
-		//	}
-		//
-		//	if opt.DisableMTUDiscovery {
-		//		query.Set("disable-mtu-discovery", "true")
-		//	}
-		//
-		//	if opt.UDPOverStreamVersion != 0 {
-		//		query.Set("udp-over-stream-version", strconv.Itoa(opt.UDPOverStreamVersion))
-		//	}
-		//
-		//	u.RawQuery = urlx.SortQuery(query).Encode()
-		//
-		//	p.vanillaLink = u.String()
-		//	p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
+	case constant.Vmess:
+		var opt *outbound.VmessOption
+		switch x := p.clashOpt.(type) {
+		case outbound.VmessOption:
+			opt = &x
+		case *outbound.VmessOption:
+			opt = x
+		default:
+			return ""
+		}
 
+		// https://github.com/2dust/v2rayN/wiki/%E5%88%86%E4%BA%AB%E9%93%BE%E6%8E%A5%E6%A0%BC%E5%BC%8F%E8%AF%B4%E6%98%8E(ver-2)
+		m := map[string]string{
+			"v":    "2",
+			"ps":   p.Name(),
+			"add":  opt.Server,
+			"port": strconv.Itoa(opt.Port),
+			"id":   opt.UUID,
+			"aid":  strconv.Itoa(opt.AlterID),
+			"scy":  opt.Cipher,
+			"net":  opt.Network,
+			"type": "none",
+			"sni":  opt.ServerName,
+			"alpn": strings.Join(opt.ALPN, ","),
+			"fp":   opt.ClientFingerprint,
+		}
+
+		if m["net"] == "" {
+			m["net"] = "tcp"
+		}
+
+		if opt.TLS {
+			m["tls"] = "tls"
+		}
+
+		if opt.SkipCertVerify {
+			m["allowInsecure"] = "1"
+		}
+
+		switch opt.Network {
+		case "ws":
+			m["host"] = opt.WSOpts.Headers["Host"]
+			m["path"] = opt.WSOpts.Path
+		case "h2":
+			m["host"] = strings.Join(opt.HTTP2Opts.Host, ",")
+			m["path"] = opt.HTTP2Opts.Path
+		case "grpc":
+			m["host"] = opt.ServerName
+			m["path"] = opt.GrpcOpts.GrpcServiceName
+		case "http":
+			m["net"] = "tcp"
+			m["type"] = "http"
+			m["host"] = strings.Join(opt.HTTPOpts.Headers["Host"], ",")
+			m["path"] = strings.Join(opt.HTTPOpts.Path, ",")
+		}
+
+		buf, err := json.Marshal(m)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return ""
+		}
+
+		return "vmess://" + base64.StdEncoding.EncodeToString(buf)
+
+	case constant.Vless:
+		var opt *outbound.VlessOption
+		switch x := p.clashOpt.(type) {
+		case outbound.VlessOption:
+			opt = &x
+		case *outbound.VlessOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		// https://github.com/XTLS/Xray-core/discussions/716
+		u := &url.URL{
+			Scheme: "vless",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+			User:   url.User(opt.UUID),
+		}
+
+		query := u.Query()
+		query.Set("encryption", "none")
+
+		query.Set("type", opt.Network)
+		if opt.Network == "" {
+			query.Set("type", "tcp")
+		}
+
+		switch {
+		case opt.RealityOpts.PublicKey != "":
+			query.Set("security", "reality")
+			query.Set("pbk", opt.RealityOpts.PublicKey)
+			if opt.RealityOpts.ShortID != "" {
+				query.Set("sid", opt.RealityOpts.ShortID)
+			}
+		case opt.TLS:
+			query.Set("security", "tls")
+		default:
+			query.Set("security", "none")
+		}
+
+		if opt.ServerName != "" {
+			query.Set("sni", opt.ServerName)
+		}
+
+		if opt.ClientFingerprint != "" {
+			query.Set("fp", opt.ClientFingerprint)
+		}
+
+		if len(opt.ALPN) > 0 {
+			query.Set("alpn", strings.Join(opt.ALPN, ","))
+		}
+
+		if opt.Flow != "" {
+			query.Set("flow", opt.Flow)
+		}
+
+		if opt.PacketEncoding != "" {
+			query.Set("packetEncoding", opt.PacketEncoding)
+		}
+
+		if opt.SkipCertVerify {
+			query.Set("allowInsecure", "1")
+		}
+
+		switch opt.Network {
+		case "ws":
+			if opt.WSOpts.V2rayHttpUpgrade {
+				query.Set("type", "httpupgrade")
+			}
+
+			path, host := opt.WSOpts.Path, opt.WSOpts.Headers["Host"]
+			if path == "" {
+				path = opt.WSPath
+			}
+			if host == "" {
+				host = opt.WSHeaders["Host"]
+			}
+
+			if opt.WSOpts.MaxEarlyData > 0 && opt.WSOpts.EarlyDataHeaderName == "Sec-WebSocket-Protocol" {
+				path = joinEarlyData(path, opt.WSOpts.MaxEarlyData)
+			}
+
+			if path != "" {
+				query.Set("path", path)
+			}
+
+			if host != "" {
+				query.Set("host", host)
+			}
+		case "grpc":
+			if opt.GrpcOpts.GrpcServiceName != "" {
+				query.Set("serviceName", opt.GrpcOpts.GrpcServiceName)
+			}
+		case "h2":
+			if opt.HTTP2Opts.Path != "" {
+				query.Set("path", opt.HTTP2Opts.Path)
+			}
+
+			if len(opt.HTTP2Opts.Host) > 0 {
+				query.Set("host", strings.Join(opt.HTTP2Opts.Host, ","))
+			}
+		case "http":
+			query.Set("type", "tcp")
+			query.Set("headerType", "http")
+
+			if len(opt.HTTPOpts.Path) > 0 {
+				query.Set("path", strings.Join(opt.HTTPOpts.Path, ","))
+			}
+
+			if len(opt.HTTPOpts.Headers["Host"]) > 0 {
+				query.Set("host", strings.Join(opt.HTTPOpts.Headers["Host"], ","))
+			}
+		}
+
+		u.RawQuery = query.Encode()
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.Trojan:
+		var opt *outbound.TrojanOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TrojanOption:
+			opt = &x
+		case *outbound.TrojanOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		u := &url.URL{
+			Scheme: "trojan",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+			User:   url.User(opt.Password),
+		}
+
+		query := u.Query()
+
+		if opt.Network != "" {
+			query.Set("type", opt.Network)
+		}
+
+		if opt.RealityOpts.PublicKey != "" {
+			query.Set("security", "reality")
+			query.Set("pbk", opt.RealityOpts.PublicKey)
+			if opt.RealityOpts.ShortID != "" {
+				query.Set("sid", opt.RealityOpts.ShortID)
+			}
+		} else {
+			query.Set("security", "tls")
+		}
+
+		if opt.SNI != "" {
+			query.Set("sni", opt.SNI)
+		}
+
+		if opt.ClientFingerprint != "" {
+			query.Set("fp", opt.ClientFingerprint)
+		}
+
+		if len(opt.ALPN) > 0 {
+			query.Set("alpn", strings.Join(opt.ALPN, ","))
+		}
+
+		if opt.SkipCertVerify {
+			query.Set("allowInsecure", "1")
+		}
+
+		switch opt.Network {
+		case "ws":
+			path := opt.WSOpts.Path
+			if opt.WSOpts.MaxEarlyData > 0 && opt.WSOpts.EarlyDataHeaderName == "Sec-WebSocket-Protocol" {
+				path = joinEarlyData(path, opt.WSOpts.MaxEarlyData)
+			}
+
+			if path != "" {
+				query.Set("path", path)
+			}
+
+			if value := opt.WSOpts.Headers["Host"]; value != "" {
+				query.Set("host", value)
+			}
+		case "grpc":
+			if opt.GrpcOpts.GrpcServiceName != "" {
+				query.Set("serviceName", opt.GrpcOpts.GrpcServiceName)
+			}
+		}
+
+		u.RawQuery = query.Encode()
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.Hysteria:
+		var opt *outbound.HysteriaOption
+		switch x := p.clashOpt.(type) {
+		case outbound.HysteriaOption:
+			opt = &x
+		case *outbound.HysteriaOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		// https://v1.hysteria.network/docs/uri-scheme/
+		u := &url.URL{
+			Scheme: "hysteria",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+		}
+
+		query := u.Query()
+
+		if opt.Protocol != "" {
+			query.Set("protocol", opt.Protocol)
+		}
+
+		if opt.AuthString != "" {
+			query.Set("auth", opt.AuthString)
+		}
+
+		if opt.SNI != "" {
+			query.Set("peer", opt.SNI)
+		}
+
+		if opt.SkipCertVerify {
+			query.Set("insecure", "1")
+		}
+
+		if opt.UpSpeed != 0 {
+			query.Set("upmbps", strconv.Itoa(opt.UpSpeed))
+		} else if up := outbound.StringToBps(opt.Up); up != 0 {
+			query.Set("upmbps", strconv.FormatUint(up/125000, 10))
+		}
+
+		if opt.DownSpeed != 0 {
+			query.Set("downmbps", strconv.Itoa(opt.DownSpeed))
+		} else if down := outbound.StringToBps(opt.Down); down != 0 {
+			query.Set("downmbps", strconv.FormatUint(down/125000, 10))
+		}
+
+		if len(opt.ALPN) > 0 {
+			query.Set("alpn", strings.Join(opt.ALPN, ","))
+		}
+
+		if opt.Obfs != "" {
+			query.Set("obfs", "xplus")
+			query.Set("obfsParam", opt.Obfs)
+		}
+
+		if opt.Ports != "" {
+			query.Set("mport", opt.Ports)
+		}
+
+		u.RawQuery = query.Encode()
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.Hysteria2:
+		var opt *outbound.Hysteria2Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Hysteria2Option:
+			opt = &x
+		case *outbound.Hysteria2Option:
+			opt = x
+		default:
+			return ""
+		}
+
+		// https://v2.hysteria.network/docs/developers/URI-Scheme/
+		u := &url.URL{
+			Scheme: "hysteria2",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+		}
+
+		if opt.Password != "" {
+			u.User = url.User(opt.Password)
+		}
+
+		query := u.Query()
+
+		if opt.SNI != "" {
+			query.Set("sni", opt.SNI)
+		}
+
+		if opt.SkipCertVerify {
+			query.Set("insecure", "1")
+		}
+
+		if opt.Obfs != "" {
+			query.Set("obfs", opt.Obfs)
+			query.Set("obfs-password", opt.ObfsPassword)
+		}
+
+		if opt.Fingerprint != "" {
+			query.Set("pinSHA256", opt.Fingerprint)
+		}
+
+		if len(opt.ALPN) > 0 {
+			query.Set("alpn", strings.Join(opt.ALPN, ","))
+		}
+
+		u.RawQuery = query.Encode()
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.WireGuard:
+		var opt *outbound.WireGuardOption
+		switch x := p.clashOpt.(type) {
+		case outbound.WireGuardOption:
+			opt = &x
+		case *outbound.WireGuardOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		u := &url.URL{
+			Scheme: "wireguard",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+			User:   url.User(opt.PrivateKey),
+		}
+
+		query := u.Query()
+
+		if opt.PublicKey != "" {
+			query.Set("publickey", opt.PublicKey)
+		}
+
+		if opt.PreSharedKey != "" {
+			query.Set("presharedkey", opt.PreSharedKey)
+		}
+
+		if len(opt.Reserved) > 0 {
+			query.Set("reserved", strings.Join(pie.Map(opt.Reserved, func(b uint8) string {
+				return strconv.Itoa(int(b))
+			}), ","))
+		}
+
+		var address []string
+		if opt.Ip != "" {
+			address = append(address, opt.Ip)
+		}
+		if opt.Ipv6 != "" {
+			address = append(address, opt.Ipv6)
+		}
+		if len(address) > 0 {
+			query.Set("address", strings.Join(address, ","))
+		}
+
+		if opt.MTU != 0 {
+			query.Set("mtu", strconv.Itoa(opt.MTU))
+		}
+
+		u.RawQuery = query.Encode()
+		u.Fragment = p.Name()
+
+		return u.String()
+
+	case constant.Tuic:
+		var opt *outbound.TuicOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TuicOption:
+			opt = &x
+		case *outbound.TuicOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		u := &url.URL{
+			Scheme: "tuic",
+			Host:   net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)),
+		}
+
+		if opt.Token != "" {
+			u.User = url.User(opt.Token)
+		} else {
+			u.User = url.UserPassword(opt.UUID, opt.Password)
+		}
+
+		query := u.Query()
+
+		if opt.SNI != "" {
+			query.Set("sni", opt.SNI)
+		}
+
+		if opt.CongestionController != "" {
+			query.Set("congestion_control", opt.CongestionController)
+		}
+
+		if opt.UdpRelayMode != "" {
+			query.Set("udp_relay_mode", opt.UdpRelayMode)
+		}
+
+		if len(opt.ALPN) > 0 {
+			query.Set("alpn", strings.Join(opt.ALPN, ","))
+		}
+
+		if opt.DisableSni {
+			query.Set("disable_sni", "1")
+		}
+
+		if opt.ReduceRtt {
+			query.Set("reduce_rtt", "1")
+		}
+
+		if opt.SkipCertVerify {
+			query.Set("allow_insecure", "1")
+		}
+
+		u.RawQuery = query.Encode()
+		u.Fragment = p.Name()
+
+		return u.String()
 	}
 
 	return ""
@@ -1,11 +1,13 @@
 package adapter_test
 
 import (
+	"encoding/base64"
 	"github.com/ice-cream-heaven/vanilla/adapter"
 	"gopkg.in/yaml.v3"
 	"io"
 	"net/http"
 	"net/url"
+	"reflect"
 	"testing"
 )
 
@@ -95,3 +97,24 @@ func TestClash(t *testing.T) {
 
 	t.Log(a.Addr())
 }
+
+func TestToClashSlice(t *testing.T) {
+	a, err := adapter.ParseLink("hysteria://1.2.3.4:443?protocol=udp&auth=pass&peer=example.com&upmbps=10&downmbps=50&alpn=h3,hq#a")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if alpn := a.ToClash()["alpn"]; !reflect.DeepEqual(alpn, []any{"h3", "hq"}) {
+		t.Errorf("alpn = %v", alpn)
+	}
+
+	vmess := base64.StdEncoding.EncodeToString([]byte(`{"v":"2","ps":"b","add":"1.2.3.4","port":"443","id":"418048af-a293-4b99-9b0c-98ca3580dd24","aid":"0","scy":"auto","net":"h2","host":"example.com","path":"/h2","tls":"tls"}`))
+	b, err := adapter.ParseLink("vmess://" + vmess)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if h2, _ := b.ToClash()["h2-opts"].(map[string]any); !reflect.DeepEqual(h2["host"], []any{"example.com"}) {
+		t.Errorf("h2-opts = %v", b.ToClash()["h2-opts"])
+	}
+}
@@ -0,0 +1,120 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"github.com/metacubex/mihomo/component/dialer"
+	"github.com/metacubex/mihomo/component/proxydialer"
+	"github.com/metacubex/mihomo/constant"
+	"golang.org/x/exp/maps"
+	"strings"
+)
+
+var ErrChainUnsupported = errors.New("proxy does not support chain")
+
+// chainProxy 通过 via 连接到出口节点，相当于 clash 的 dialer-proxy
+type chainProxy struct {
+	constant.ProxyAdapter // 出口节点
+
+	name string
+	// via 前一跳，可能也是链式节点
+	via *Adapter
+}
+
+func supportNetwork(support, network constant.NetWork) bool {
+	return support == constant.ALLNet || support == network
+}
+
+func (c *chainProxy) Name() string {
+	return c.name
+}
+
+func (c *chainProxy) DialContext(ctx context.Context, metadata *constant.Metadata, opts ...dialer.Option) (constant.Conn, error) {
+	return c.DialContextWithDialer(ctx, dialer.NewDialer(opts...), metadata)
+}
+
+func (c *chainProxy) DialContextWithDialer(ctx context.Context, d constant.Dialer, metadata *constant.Metadata) (constant.Conn, error) {
+	if !supportNetwork(c.ProxyAdapter.SupportWithDialer(), constant.TCP) {
+		return nil, fmt.Errorf("%w:%s", ErrChainUnsupported, c.ProxyAdapter.Name())
+	}
+	return c.ProxyAdapter.DialContextWithDialer(ctx, proxydialer.New(c.via, d, false), metadata)
+}
+
+func (c *chainProxy) ListenPacketContext(ctx context.Context, metadata *constant.Metadata, opts ...dialer.Option) (constant.PacketConn, error) {
+	return c.ListenPacketWithDialer(ctx, dialer.NewDialer(opts...), metadata)
+}
+
+func (c *chainProxy) ListenPacketWithDialer(ctx context.Context, d constant.Dialer, metadata *constant.Metadata) (constant.PacketConn, error) {
+	if !supportNetwork(c.ProxyAdapter.SupportWithDialer(), constant.UDP) {
+		return nil, fmt.Errorf("%w:%s", ErrChainUnsupported, c.ProxyAdapter.Name())
+	}
+	return c.ProxyAdapter.ListenPacketWithDialer(ctx, proxydialer.New(c.via, d, false), metadata)
+}
+
+// NewChain 依次通过 hops 中的节点连接目标，第一个节点直接连接，之后的每个节点都通过前一个节点连接，
+// 名称为各节点名称用 " -> " 连接，unique_id 由各节点的 unique_id 生成，与名称无关。
+// ToClash 导出的是最后一个节点，dialer-proxy 为前一个节点的名称，需要使用 ToClashChain 导出全部节点，ToV2ray 不支持链式节点
+func NewChain(hops ...*Adapter) (*Adapter, error) {
+	if len(hops) < 2 {
+		return nil, errors.New("chain requires at least two hops")
+	}
+
+	ids := []string{hops[0].UniqueId()}
+
+	via, prev := hops[0], hops[0].clashName()
+	for _, hop := range hops[1:] {
+		if hop.SupportWithDialer() == constant.InvalidNet {
+			return nil, fmt.Errorf("%w:%s", ErrChainUnsupported, hop.Name())
+		}
+
+		ids = append(ids, hop.UniqueId())
+
+		p := &Adapter{
+			ProxyAdapter: &chainProxy{
+				ProxyAdapter: hop,
+				name:         via.Name() + " -> " + hop.Name(),
+				via:          via,
+			},
+			opt:      map[string]any{},
+			uniqueId: fmt.Sprintf("%x", sha512.Sum512([]byte(strings.Join(ids, ",")))),
+			history:  newURLTestHistory(DefaultURLTestHistorySize),
+		}
+
+		maps.Copy(p.opt, hop.opt)
+		// NOTE: 导出时每一跳使用各自的名称，dialer-proxy 指向前一跳导出的名称
+		p.opt["name"] = hop.Name()
+		p.opt["dialer-proxy"] = prev
+
+		p.initClient()
+
+		via, prev = p, p.clashName()
+	}
+
+	return via, nil
+}
+
+// ToClashChain 返回链式节点导出 clash 时需要的全部节点，前面的节点使用各自的名称并设置了 dialer-proxy，
+// 最后一个为 ToClash 的结果。非链式节点只返回 ToClash 的结果
+func (p *Adapter) ToClashChain() []map[string]any {
+	c, ok := p.ProxyAdapter.(*chainProxy)
+	if !ok {
+		return []map[string]any{p.ToClash()}
+	}
+
+	list := c.via.ToClashChain()
+	list[len(list)-1]["name"] = c.via.clashName()
+
+	return append(list, p.ToClash())
+}
+
+// clashName 作为前一跳导出时的名称，链式节点使用最后一跳的名称
+func (p *Adapter) clashName() string {
+	if _, ok := p.ProxyAdapter.(*chainProxy); ok {
+		if name, ok := p.opt["name"].(string); ok {
+			return name
+		}
+	}
+	return p.Name()
+}
@@ -0,0 +1,157 @@
+package adapter_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/elliotchance/pie/v2"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewChain(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	proxyA, proxyB := newSocks5Proxy(t), newSocks5Proxy(t)
+	a, b := proxyA.Adapter(t).WithName("a"), proxyB.Adapter(t).WithName("b")
+
+	if _, err := adapter.NewChain(a); err == nil {
+		t.Fatal("expected error for single hop")
+	}
+
+	chain, err := adapter.NewChain(a, b)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if chain.Name() != "a -> b" {
+		t.Errorf("name = %s", chain.Name())
+	}
+
+	reversed, err := adapter.NewChain(b, a)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	// unique_id 与顺序有关，与名称无关
+	switch chain.UniqueId() {
+	case "", a.UniqueId(), b.UniqueId(), reversed.UniqueId():
+		t.Errorf("unique_id = %s", chain.UniqueId())
+	}
+	if renamed, _ := adapter.NewChain(a.WithName("x"), b.WithName("y")); renamed.UniqueId() != chain.UniqueId() {
+		t.Errorf("unique_id changed after rename")
+	}
+
+	if m := chain.ToClash(); m["dialer-proxy"] != "a" || m["name"] != "a -> b" || m["type"] != "socks5" {
+		t.Errorf("clash = %v", m)
+	}
+
+	resp, err := chain.GetClientWithTimeout(time.Second * 5).Get(target.URL)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("body = %s", body)
+	}
+
+	// a 连接 b，b 连接目标
+	if proxyA.conns.Load() != 1 || proxyB.conns.Load() != 1 {
+		t.Errorf("conns = %d, %d", proxyA.conns.Load(), proxyB.conns.Load())
+	}
+
+	if result := chain.URLTest(context.Background(), target.URL, http.StatusOK); !result.Ok() {
+		t.Errorf("result = %+v", result)
+	}
+
+	resp2, err := chain.R().Get(target.URL)
+	if err != nil || resp2.String() != "ok" {
+		t.Errorf("resp = %v, err:%v", resp2, err)
+	}
+
+	// 三跳，第一跳不可用时整条链失败
+	proxyC := newSocks5Proxy(t)
+	long, err := adapter.NewChain(a, b, proxyC.Adapter(t).WithName("c"))
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if long.Name() != "a -> b -> c" {
+		t.Errorf("name = %s", long.Name())
+	}
+	if result := long.URLTest(context.Background(), target.URL, 0); !result.Ok() || proxyC.conns.Load() != 1 {
+		t.Errorf("result = %+v, conns = %d", result, proxyC.conns.Load())
+	}
+
+	// 导出后每个 dialer-proxy 都指向前一跳
+	profile, err := yaml.Marshal(map[string]any{"proxies": long.ToClashChain()})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	report := adapter.ParseSubscriptionReport(profile)
+	if len(report.Adapters) != 3 || len(report.Rejections) != 0 {
+		t.Fatalf("adapters = %v, rejections = %v", report.Adapters, report.Rejections)
+	}
+
+	dialerProxies := func(list []map[string]any) []string {
+		var got []string
+		for _, m := range list {
+			got = append(got, fmt.Sprintf("%s<%v", m["name"], m["dialer-proxy"]))
+		}
+		return got
+	}
+
+	got := dialerProxies(pie.Map(report.Adapters, (*adapter.Adapter).ToClash))
+	if want := []string{"a<<nil>", "b<a", "a -> b -> c<b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// 第一跳本身是链式节点，包括重命名后的链式节点
+	c := proxyC.Adapter(t).WithName("c")
+	nested, err := adapter.NewChain(chain, c)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if got := dialerProxies(nested.ToClashChain()); !reflect.DeepEqual(got, []string{"a<<nil>", "b<a", "a -> b -> c<b"}) {
+		t.Errorf("got %v", got)
+	}
+
+	renamed, err := adapter.NewChain(chain.WithName("ab"), c)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if got := dialerProxies(renamed.ToClashChain()); !reflect.DeepEqual(got, []string{"a<<nil>", "b<a", "ab -> c<b"}) {
+		t.Errorf("got %v", got)
+	}
+
+	// 生成 clash 配置时同时导出前面的节点，与其他节点重名时追加编号
+	b2, err := adapter.ToClashProfile([]*adapter.Adapter{b, long}, adapter.ClashProfileOption{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	var clash struct {
+		Proxies []map[string]any `yaml:"proxies"`
+	}
+	err = yaml.Unmarshal(b2, &clash)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if got := dialerProxies(clash.Proxies); !reflect.DeepEqual(got, []string{"b<<nil>", "a<<nil>", "b 2<a", "a -> b -> c<b 2"}) {
+		t.Errorf("got %v", got)
+	}
+
+	_ = proxyA.Close()
+	if result := long.URLTest(context.Background(), target.URL, 0); result.Ok() {
+		t.Errorf("result = %+v", result)
+	}
+}
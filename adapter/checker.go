@@ -0,0 +1,239 @@
+package adapter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FailureKind 测速失败的原因
+type FailureKind string
+
+const (
+	FailureNone    FailureKind = ""
+	FailureDNS     FailureKind = "dns"
+	FailureRefused FailureKind = "refused"
+	FailureTimeout FailureKind = "timeout"
+	FailureTLS     FailureKind = "tls"
+	FailureStatus  FailureKind = "status"
+	FailureUnknown FailureKind = "unknown"
+)
+
+const (
+	DefaultCheckConcurrency = 16
+	DefaultCheckTimeout     = time.Second * 5
+	DefaultCheckRetryDelay  = time.Second
+)
+
+// ClassifyFailure 根据 URLTest 返回的错误判断失败的原因，mihomo 的部分错误只有文本，需要按内容匹配
+func ClassifyFailure(err error) FailureKind {
+	if err == nil {
+		return FailureNone
+	}
+
+	if errors.Is(err, ErrUnexpectedStatus) {
+		return FailureStatus
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && !dnsErr.IsTimeout {
+		return FailureDNS
+	}
+	if strings.Contains(msg, "no such host") || strings.Contains(msg, "dns resolve failed") {
+		return FailureDNS
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(msg, "connection refused") {
+		return FailureRefused
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) || strings.Contains(msg, "timeout") {
+		return FailureTimeout
+	}
+
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &verifyErr),
+		errors.As(err, &recordErr),
+		errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr),
+		strings.Contains(msg, "tls:"), strings.Contains(msg, "x509:"):
+		return FailureTLS
+	}
+
+	return FailureUnknown
+}
+
+// CheckOption 批量测速的选项，零值即可使用
+type CheckOption struct {
+	URL            string // 默认为 DefaultClashTestURL
+	ExpectedStatus int    // 为 0 时 400 以下的状态码均视为成功
+
+	Concurrency int           // 默认为 DefaultCheckConcurrency
+	Timeout     time.Duration // 每次尝试的超时时间，默认为 DefaultCheckTimeout
+
+	// Retries 失败后额外重试的次数，状态码不符合预期时不会重试
+	Retries int
+	// RetryDelay 重试前等待的时间，默认为 DefaultCheckRetryDelay
+	RetryDelay time.Duration
+	// Jitter 每次尝试前额外随机等待 [0, Jitter)，避免同时发起大量请求
+	Jitter time.Duration
+}
+
+type CheckResult struct {
+	Node *Adapter
+	// Result 最后一次尝试的结果
+	Result   *URLTestResult
+	Attempts int
+	Failure  FailureKind
+}
+
+func (r *CheckResult) Ok() bool {
+	return r.Failure == FailureNone
+}
+
+// CheckNodes 并发测速，每个节点完成后调用 fn（不会并发调用，可以为 nil），
+// 返回的结果与 nodes 的顺序一致，ctx 取消后未开始的节点不会出现在结果中
+func CheckNodes(ctx context.Context, nodes []*Adapter, opt CheckOption, fn func(result *CheckResult)) ([]*CheckResult, error) {
+	if opt.URL == "" {
+		opt.URL = DefaultClashTestURL
+	}
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = DefaultCheckConcurrency
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = DefaultCheckTimeout
+	}
+	if opt.RetryDelay <= 0 {
+		opt.RetryDelay = DefaultCheckRetryDelay
+	}
+
+	results := make([]*CheckResult, len(nodes))
+
+	var (
+		wg       sync.WaitGroup
+		callback sync.Mutex
+		sem      = make(chan struct{}, opt.Concurrency)
+	)
+
+	for i, node := range nodes {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, node *Adapter) {
+			defer wg.Done()
+			defer func() {
+				<-sem
+			}()
+
+			result := checkNode(ctx, node, opt)
+			results[i] = result
+
+			if fn != nil {
+				callback.Lock()
+				fn(result)
+				callback.Unlock()
+			}
+		}(i, node)
+	}
+
+	wg.Wait()
+
+	list := make([]*CheckResult, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			list = append(list, result)
+		}
+	}
+
+	return list, ctx.Err()
+}
+
+func checkNode(ctx context.Context, node *Adapter, opt CheckOption) *CheckResult {
+	result := &CheckResult{
+		Node: node,
+	}
+
+	for {
+		wait := jitter(opt.Jitter)
+		if result.Attempts > 0 {
+			wait += opt.RetryDelay
+		}
+
+		if !sleepContext(ctx, wait) {
+			if result.Result == nil {
+				result.Result = &URLTestResult{URL: opt.URL, Err: ctx.Err(), At: time.Now()}
+				result.Failure = ClassifyFailure(ctx.Err())
+			}
+			return result
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, opt.Timeout)
+		result.Result = node.URLTest(attemptCtx, opt.URL, opt.ExpectedStatus)
+		cancel()
+
+		result.Attempts++
+		result.Failure = ClassifyFailure(result.Result.Err)
+
+		if result.Ok() || result.Failure == FailureStatus || result.Attempts > opt.Retries || ctx.Err() != nil {
+			return result
+		}
+	}
+}
+
+// AliveNodes 测速成功的节点
+func AliveNodes(results []*CheckResult) []*Adapter {
+	var nodes []*Adapter
+	for _, result := range results {
+		if result.Ok() {
+			nodes = append(nodes, result.Node)
+		}
+	}
+	return nodes
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
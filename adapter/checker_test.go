@@ -0,0 +1,156 @@
+package adapter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want adapter.FailureKind
+	}{
+		{err: nil, want: adapter.FailureNone},
+		{err: fmt.Errorf("%w:%d", adapter.ErrUnexpectedStatus, 500), want: adapter.FailureStatus},
+		{err: &net.DNSError{Err: "no such host", Name: "example.invalid"}, want: adapter.FailureDNS},
+		{err: errors.New("dns resolve failed: couldn't find ip"), want: adapter.FailureDNS},
+		{err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, want: adapter.FailureRefused},
+		{err: context.DeadlineExceeded, want: adapter.FailureTimeout},
+		{err: errors.New("net/http: TLS handshake timeout"), want: adapter.FailureTimeout},
+		{err: errors.New("tls: first record does not look like a TLS handshake"), want: adapter.FailureTLS},
+		{err: errors.New("EOF"), want: adapter.FailureUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := adapter.ClassifyFailure(tt.err); got != tt.want {
+			t.Errorf("ClassifyFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCheckNodes(t *testing.T) {
+	var flaky atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			// 第一次请求超时
+			if flaky.Add(1) == 1 {
+				time.Sleep(time.Millisecond * 500)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer target.Close()
+
+	tlsTarget := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsTarget.Close()
+
+	proxy := newSocks5Proxy(t)
+	good := proxy.Adapter(t)
+
+	// 关闭的端口
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	refusedAddr := l.Addr().String()
+	_ = l.Close()
+
+	refused, err := adapter.ParseLink("socks5://" + refusedAddr + "#refused")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	nodes := []*adapter.Adapter{good, refused}
+
+	var called atomic.Int32
+	results, err := adapter.CheckNodes(context.Background(), nodes, adapter.CheckOption{
+		URL:            target.URL,
+		ExpectedStatus: http.StatusNoContent,
+		Concurrency:    2,
+		Timeout:        time.Millisecond * 200,
+		Retries:        1,
+		RetryDelay:     time.Millisecond * 10,
+		Jitter:         time.Millisecond * 10,
+	}, func(result *adapter.CheckResult) {
+		called.Add(1)
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if called.Load() != 2 || len(results) != 2 {
+		t.Fatalf("called:%d results:%d", called.Load(), len(results))
+	}
+
+	if !results[0].Ok() || results[0].Attempts != 1 || results[0].Node != good {
+		t.Errorf("good = %+v", results[0])
+	}
+
+	if results[1].Failure != adapter.FailureRefused || results[1].Attempts != 2 {
+		t.Errorf("refused = %+v err:%v", results[1], results[1].Result.Err)
+	}
+
+	alive := adapter.AliveNodes(results)
+	if len(alive) != 1 || alive[0] != good {
+		t.Errorf("alive = %v", alive)
+	}
+
+	tests := []struct {
+		name     string
+		opt      adapter.CheckOption
+		failure  adapter.FailureKind
+		attempts int
+	}{
+		{
+			name:     "status",
+			opt:      adapter.CheckOption{URL: target.URL, ExpectedStatus: http.StatusOK, Retries: 2},
+			failure:  adapter.FailureStatus,
+			attempts: 1,
+		},
+		{
+			name:     "tls",
+			opt:      adapter.CheckOption{URL: tlsTarget.URL},
+			failure:  adapter.FailureTLS,
+			attempts: 1,
+		},
+		{
+			name:     "retry",
+			opt:      adapter.CheckOption{URL: target.URL + "/flaky", Timeout: time.Millisecond * 200, Retries: 2, RetryDelay: time.Millisecond},
+			failure:  adapter.FailureNone,
+			attempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := adapter.CheckNodes(context.Background(), []*adapter.Adapter{good}, tt.opt, nil)
+			if err != nil || len(results) != 1 {
+				t.Fatalf("results:%v err:%v", results, err)
+			}
+
+			if results[0].Failure != tt.failure || results[0].Attempts != tt.attempts {
+				t.Errorf("failure:%v attempts:%d err:%v", results[0].Failure, results[0].Attempts, results[0].Result.Err)
+			}
+		})
+	}
+
+	// 取消后不再开始新的节点
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = adapter.CheckNodes(ctx, nodes, adapter.CheckOption{URL: target.URL}, nil)
+	if !errors.Is(err, context.Canceled) || len(results) != 0 {
+		t.Errorf("results:%v err:%v", results, err)
+	}
+}
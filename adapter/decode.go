@@ -13,10 +13,10 @@ var whiteTag = map[string]bool{
 	"alterId": true,
 }
 
-func decodeSlice(dst []any, src any) error {
+func decodeSlice(src any) (dst []any, err error) {
 	t := reflect.TypeOf(src)
 	if t.Kind() != reflect.Slice {
-		panic("src is not map")
+		panic("src is not slice")
 	}
 
 	v := reflect.ValueOf(src)
@@ -65,18 +65,17 @@ func decodeSlice(dst []any, src any) error {
 			err := decodeMap(m, lv.Interface())
 			if err != nil {
 				log.Errorf("err:%v", err)
-				return err
+				return nil, err
 			}
 			if len(m) == 0 {
 				continue
 			}
 			dst = append(dst, m)
 		case reflect.Slice:
-			var l []any
-			err := decodeSlice(l, lv.Interface())
+			l, err := decodeSlice(lv.Interface())
 			if err != nil {
 				log.Errorf("err:%v", err)
-				return err
+				return nil, err
 			}
 			if len(l) == 0 {
 				continue
@@ -92,7 +91,7 @@ func decodeSlice(dst []any, src any) error {
 			err := decode(m, lv.Interface())
 			if err != nil {
 				log.Errorf("err:%v", err)
-				return err
+				return nil, err
 			}
 			if len(m) == 0 {
 				continue
@@ -103,7 +102,7 @@ func decodeSlice(dst []any, src any) error {
 		}
 	}
 
-	return nil
+	return dst, nil
 }
 
 func decodeMap(dst map[string]any, src any) error {
@@ -163,8 +162,7 @@ func decodeMap(dst map[string]any, src any) error {
 			}
 			dst[mk] = m
 		case reflect.Slice:
-			var l []any
-			err := decodeSlice(l, mv.Interface())
+			l, err := decodeSlice(mv.Interface())
 			if err != nil {
 				log.Errorf("err:%v", err)
 				return err
@@ -265,8 +263,7 @@ func decode(dst map[string]any, src any) error {
 			}
 			dst[tag] = m
 		case reflect.Slice:
-			var l []any
-			err := decodeSlice(l, fv.Interface())
+			l, err := decodeSlice(fv.Interface())
 			if err != nil {
 				return err
 			}
@@ -280,6 +277,15 @@ func decode(dst map[string]any, src any) error {
 			}
 			dst[tag] = fv.String()
 		case reflect.Struct, reflect.Ptr:
+			// NOTE: 匿名嵌入的结构体（如 BasicOption、WireGuardPeerOption）字段直接展开
+			if ft.Anonymous && tag == "" {
+				err := decode(dst, fv.Interface())
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
 			m := map[string]any{}
 			err := decode(m, fv.Interface())
 			if err != nil {
@@ -338,6 +344,9 @@ func ToOption(src any) any {
 			case "hysteria":
 				opt = &outbound.HysteriaOption{}
 
+			case "hysteria2":
+				opt = &outbound.Hysteria2Option{}
+
 			case "wireguard":
 				opt = &outbound.WireGuardOption{}
 
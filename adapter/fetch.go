@@ -0,0 +1,290 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"github.com/ice-cream-heaven/log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSubscriptionUserAgent      = "clash.meta"
+	DefaultSubscriptionUpdateInterval = time.Hour * 24
+)
+
+// SubscriptionUserInfo 订阅的流量信息，来自 subscription-userinfo 响应头，单位为字节
+type SubscriptionUserInfo struct {
+	Upload   int64
+	Download int64
+	Total    int64
+
+	// Expire 到期时间，未设置时为零值
+	Expire time.Time
+}
+
+// Subscription 远程订阅，支持 ETag、Last-Modified 以及 profile-update-interval
+type Subscription struct {
+	url       string
+	userAgent string
+	interval  time.Duration
+	proxy     *Adapter
+	opts      []ParseOption
+
+	lock sync.RWMutex
+
+	etag         string
+	lastModified string
+	body         []byte
+	report       *SubscriptionReport
+	userInfo     *SubscriptionUserInfo
+	filename     string
+
+	// 服务端通过 profile-update-interval 指定的更新间隔
+	remoteInterval time.Duration
+	updatedAt      time.Time
+}
+
+func NewSubscription(url string, opts ...ParseOption) *Subscription {
+	return &Subscription{
+		url:       url,
+		userAgent: DefaultSubscriptionUserAgent,
+		interval:  DefaultSubscriptionUpdateInterval,
+		opts:      opts,
+		report:    newSubscriptionReport(SubscriptionFormatUnknown),
+	}
+}
+
+// SetProxy 通过指定的节点拉取订阅，默认直连
+func (s *Subscription) SetProxy(p *Adapter) *Subscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.proxy = p
+	return s
+}
+
+// SetUserAgent 部分机场会根据 User-Agent 返回不同格式的订阅
+func (s *Subscription) SetUserAgent(userAgent string) *Subscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.userAgent = userAgent
+	return s
+}
+
+// SetUpdateInterval 服务端没有返回 profile-update-interval 时使用的更新间隔
+func (s *Subscription) SetUpdateInterval(interval time.Duration) *Subscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.interval = interval
+	return s
+}
+
+func (s *Subscription) URL() string {
+	return s.url
+}
+
+// Update 拉取订阅，服务端返回 304 时保留上一次的结果
+func (s *Subscription) Update(ctx context.Context) error {
+	s.lock.RLock()
+	proxy := s.proxy
+	userAgent := s.userAgent
+	etag := s.etag
+	lastModified := s.lastModified
+	s.lock.RUnlock()
+
+	if proxy == nil {
+		var err error
+		proxy, err = NewDirect()
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+	}
+
+	req := proxy.R().SetContext(ctx).SetHeader("User-Agent", userAgent)
+	if etag != "" {
+		req.SetHeader("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.SetHeader("If-Modified-Since", lastModified)
+	}
+
+	resp, err := req.Get(s.url)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		s.lock.Lock()
+		s.updatedAt = time.Now()
+		s.lock.Unlock()
+
+		log.Debugf("subscription not modified:%s", s.url)
+		return nil
+
+	default:
+		log.Errorf("subscription status:%s", resp.Status())
+		return fmt.Errorf("subscription status:%s", resp.Status())
+	}
+
+	header := resp.Header()
+	report := ParseSubscriptionReportContext(ctx, resp.Body(), s.opts...)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.etag = header.Get("ETag")
+	s.lastModified = header.Get("Last-Modified")
+	s.body = resp.Body()
+	s.report = report
+	s.userInfo = parseSubscriptionUserInfo(header.Get("Subscription-Userinfo"))
+	s.filename = parseContentDispositionFilename(header.Get("Content-Disposition"))
+	s.remoteInterval = 0
+	if hours, err := strconv.ParseFloat(strings.TrimSpace(header.Get("Profile-Update-Interval")), 64); err == nil && hours > 0 {
+		s.remoteInterval = time.Duration(hours * float64(time.Hour))
+	}
+	s.updatedAt = time.Now()
+
+	return nil
+}
+
+// UpdateIfExpired 从未拉取或超过更新间隔时拉取订阅，返回是否发起了请求
+func (s *Subscription) UpdateIfExpired(ctx context.Context) (bool, error) {
+	if !s.Expired() {
+		return false, nil
+	}
+
+	return true, s.Update(ctx)
+}
+
+func (s *Subscription) Expired() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.updatedAt.IsZero() {
+		return true
+	}
+
+	return time.Since(s.updatedAt) >= s.updateInterval()
+}
+
+// UpdateInterval 优先使用服务端返回的 profile-update-interval
+func (s *Subscription) UpdateInterval() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.updateInterval()
+}
+
+func (s *Subscription) updateInterval() time.Duration {
+	if s.remoteInterval > 0 {
+		return s.remoteInterval
+	}
+	return s.interval
+}
+
+func (s *Subscription) UpdatedAt() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.updatedAt
+}
+
+func (s *Subscription) Nodes() []*Adapter {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.report.Adapters
+}
+
+func (s *Subscription) Report() *SubscriptionReport {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.report
+}
+
+func (s *Subscription) Body() []byte {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.body
+}
+
+// UserInfo 服务端没有返回 subscription-userinfo 时为 nil
+func (s *Subscription) UserInfo() *SubscriptionUserInfo {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.userInfo
+}
+
+// Filename content-disposition 中的文件名，通常为机场的名称
+func (s *Subscription) Filename() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.filename
+}
+
+// parseSubscriptionUserInfo 解析 upload=1234; download=2234; total=1024000; expire=2218532293
+func parseSubscriptionUserInfo(s string) *SubscriptionUserInfo {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	info := &SubscriptionUserInfo{}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+
+		// 部分机场会返回浮点数或科学计数法
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "upload":
+			info.Upload = int64(f)
+		case "download":
+			info.Download = int64(f)
+		case "total":
+			info.Total = int64(f)
+		case "expire":
+			if f > 0 {
+				info.Expire = time.Unix(int64(f), 0)
+			}
+		}
+	}
+
+	return info
+}
+
+// parseContentDispositionFilename 支持 filename 以及 RFC 5987 的 filename*
+func parseContentDispositionFilename(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	_, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return ""
+	}
+
+	return params["filename"]
+}
@@ -0,0 +1,120 @@
+package adapter_test
+
+import (
+	"context"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscription(t *testing.T) {
+	var hits, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		if r.Header.Get("User-Agent") != adapter.DefaultSubscriptionUserAgent {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Subscription-Userinfo", "upload=1024; download=2048; total=1.073741824e+10; expire=2218532293")
+		w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''%E6%9C%BA%E5%9C%BA")
+		w.Header().Set("Profile-Update-Interval", "12")
+		_, _ = w.Write([]byte("dHJvamFuOi8vcHdkQDEuMS4xLjE6NDQzI3Ryb2phbg=="))
+	}))
+	defer server.Close()
+
+	sub := adapter.NewSubscription(server.URL)
+	if !sub.Expired() {
+		t.Errorf("new subscription should be expired")
+	}
+
+	updated, err := sub.UpdateIfExpired(context.Background())
+	if err != nil || !updated {
+		t.Errorf("updated:%v err:%v", updated, err)
+		return
+	}
+
+	if len(sub.Nodes()) != 1 || sub.Nodes()[0].Name() != "trojan" {
+		t.Errorf("nodes = %v", sub.Nodes())
+	}
+
+	if sub.Report().Format != adapter.SubscriptionFormatLink {
+		t.Errorf("format = %v", sub.Report().Format)
+	}
+
+	info := sub.UserInfo()
+	if info == nil || info.Upload != 1024 || info.Download != 2048 || info.Total != 10737418240 || !info.Expire.Equal(time.Unix(2218532293, 0)) {
+		t.Errorf("user info = %+v", info)
+	}
+
+	if sub.Filename() != "机场" {
+		t.Errorf("filename = %s", sub.Filename())
+	}
+
+	if sub.UpdateInterval() != time.Hour*12 || sub.Expired() {
+		t.Errorf("interval = %v", sub.UpdateInterval())
+	}
+
+	// 未过期时不会发起请求
+	updated, err = sub.UpdateIfExpired(context.Background())
+	if err != nil || updated {
+		t.Errorf("updated:%v err:%v", updated, err)
+	}
+
+	err = sub.Update(context.Background())
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	if atomic.LoadInt32(&hits) != 2 || atomic.LoadInt32(&notModified) != 1 {
+		t.Errorf("hits:%d not modified:%d", hits, notModified)
+	}
+
+	if len(sub.Nodes()) != 1 {
+		t.Errorf("nodes should be kept after 304")
+	}
+
+	err = sub.SetUserAgent("curl").Update(context.Background())
+	if err == nil {
+		t.Errorf("want status error")
+	}
+}
+
+func TestSubscriptionProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("trojan://pwd@1.1.1.1:443#trojan"))
+	}))
+	defer server.Close()
+
+	proxy := newConnectProxy()
+	defer proxy.Close()
+
+	node, err := adapter.ParseLink("https://" + proxy.Listener.Addr().String() + "?allowInsecure=1")
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	sub := adapter.NewSubscription(server.URL).SetProxy(node)
+	err = sub.Update(context.Background())
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	if len(sub.Nodes()) != 1 || sub.UserInfo() != nil {
+		t.Errorf("nodes = %v", sub.Nodes())
+	}
+}
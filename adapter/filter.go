@@ -0,0 +1,286 @@
+package adapter
+
+import (
+	"github.com/ice-cream-heaven/log"
+	"gopkg.in/yaml.v3"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Region 节点所在的地区，通过名称中的关键字识别
+type Region struct {
+	Code string
+	Name string
+	Flag string
+
+	pattern *regexp.Regexp
+}
+
+func newRegion(code, name, flag, keywords string) *Region {
+	return &Region{
+		Code: code,
+		Name: name,
+		Flag: flag,
+		// 英文缩写需要大写并且独立出现，避免误匹配，如 USA 中不应该识别出 SA，Made in China 中不应该识别出 IN
+		pattern: regexp.MustCompile(`(?i:` + flag + `|` + name + `|` + keywords + `)|(^|[^A-Za-z])` + code + `([^A-Za-z]|$)`),
+	}
+}
+
+var Regions = []*Region{
+	newRegion("HK", "香港", "🇭🇰", `港|hong ?kong`),
+	newRegion("TW", "台湾", "🇹🇼", `台北|taiwan|taipei`),
+	newRegion("MO", "澳门", "🇲🇴", `澳门|macao|macau`),
+	newRegion("JP", "日本", "🇯🇵", `东京|大阪|japan|tokyo|osaka`),
+	newRegion("KR", "韩国", "🇰🇷", `首尔|韩|korea|seoul`),
+	newRegion("SG", "新加坡", "🇸🇬", `狮城|singapore`),
+	newRegion("US", "美国", "🇺🇸", `美|洛杉矶|硅谷|纽约|united ?states|america|los ?angeles|san ?jose|seattle`),
+	newRegion("GB", "英国", "🇬🇧", `伦敦|united ?kingdom|london`),
+	newRegion("DE", "德国", "🇩🇪", `法兰克福|germany|frankfurt`),
+	newRegion("FR", "法国", "🇫🇷", `巴黎|france|paris`),
+	newRegion("NL", "荷兰", "🇳🇱", `阿姆斯特丹|netherlands|amsterdam`),
+	newRegion("RU", "俄罗斯", "🇷🇺", `莫斯科|russia|moscow`),
+	newRegion("CA", "加拿大", "🇨🇦", `多伦多|canada|toronto`),
+	newRegion("AU", "澳大利亚", "🇦🇺", `澳洲|悉尼|australia|sydney`),
+	newRegion("IN", "印度", "🇮🇳", `孟买|india|mumbai`),
+	newRegion("TR", "土耳其", "🇹🇷", `伊斯坦布尔|turkey|istanbul`),
+}
+
+// DetectRegion 根据名称识别节点所在的地区，无法识别时返回 nil
+func DetectRegion(name string) *Region {
+	for _, region := range Regions {
+		if region.pattern.MatchString(name) {
+			return region
+		}
+	}
+	return nil
+}
+
+// NodeFilter 所有非空的条件都满足时视为匹配，均为正则表达式
+type NodeFilter struct {
+	Name   string `yaml:"name,omitempty"`
+	Type   string `yaml:"type,omitempty"`
+	Server string `yaml:"server,omitempty"`
+	Port   string `yaml:"port,omitempty"`
+	// Region 匹配识别出的地区代码，如 HK|JP
+	Region string `yaml:"region,omitempty"`
+
+	name, typ, server, port, region *regexp.Regexp
+}
+
+func (f *NodeFilter) compile() (err error) {
+	compile := func(expr string) *regexp.Regexp {
+		if expr == "" || err != nil {
+			return nil
+		}
+
+		var re *regexp.Regexp
+		re, err = regexp.Compile(expr)
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+		return re
+	}
+
+	f.name = compile(f.Name)
+	f.typ = compile(f.Type)
+	f.server = compile(f.Server)
+	f.port = compile(f.Port)
+	f.region = compile(f.Region)
+
+	return err
+}
+
+func (f *NodeFilter) Match(node *Adapter) bool {
+	if f.name != nil && !f.name.MatchString(node.Name()) {
+		return false
+	}
+
+	if f.typ != nil && !f.typ.MatchString(node.TypeString()) {
+		return false
+	}
+
+	host, port, _ := net.SplitHostPort(node.Addr())
+	if f.server != nil && !f.server.MatchString(host) {
+		return false
+	}
+
+	if f.port != nil && !f.port.MatchString(port) {
+		return false
+	}
+
+	if f.region != nil {
+		region := DetectRegion(node.Name())
+		if region == nil || !f.region.MatchString(region.Code) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NodeRename 设置 Match 时使用正则替换，设置 Template 时使用模板生成新的名称。
+// 模板支持 {name}、{type}、{id}、{host}、{port}、{index}、{region}、{code}、{flag}
+type NodeRename struct {
+	Match    string `yaml:"match,omitempty"`
+	Replace  string `yaml:"replace,omitempty"`
+	Template string `yaml:"template,omitempty"`
+
+	match *regexp.Regexp
+}
+
+func (r *NodeRename) compile() (err error) {
+	if r.Match == "" {
+		return nil
+	}
+
+	r.match, err = regexp.Compile(r.Match)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *NodeRename) rename(node *Adapter, index int) string {
+	name := node.Name()
+
+	if r.match != nil {
+		name = r.match.ReplaceAllString(name, r.Replace)
+	}
+
+	if r.Template != "" {
+		host, port, _ := net.SplitHostPort(node.Addr())
+
+		var regionName, code, flag string
+		if region := DetectRegion(name); region != nil {
+			regionName, code, flag = region.Name, region.Code, region.Flag
+		}
+
+		name = strings.NewReplacer(
+			"{name}", name,
+			"{type}", node.TypeString(),
+			"{id}", node.ShortId(),
+			"{host}", host,
+			"{port}", port,
+			"{index}", strconv.Itoa(index),
+			"{region}", regionName,
+			"{code}", code,
+			"{flag}", flag,
+		).Replace(r.Template)
+	}
+
+	return strings.TrimSpace(name)
+}
+
+// NodeRules 先过滤再重命名，Include 为空时保留所有节点，命中任意一个 Exclude 的节点会被丢弃
+type NodeRules struct {
+	Include []*NodeFilter `yaml:"include,omitempty"`
+	Exclude []*NodeFilter `yaml:"exclude,omitempty"`
+	Rename  []*NodeRename `yaml:"rename,omitempty"`
+
+	// 多个协程共用时只编译一次
+	once       sync.Once
+	compileErr error
+}
+
+func ParseNodeRules(b []byte) (*NodeRules, error) {
+	var r NodeRules
+	err := yaml.Unmarshal(b, &r)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	err = r.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Compile 编译正则表达式，只会编译一次，Apply 时会自动调用
+func (r *NodeRules) Compile() error {
+	r.once.Do(func() {
+		r.compileErr = r.compile()
+	})
+	return r.compileErr
+}
+
+func (r *NodeRules) compile() error {
+	for _, filter := range r.Include {
+		err := filter.compile()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, filter := range r.Exclude {
+		err := filter.compile()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rename := range r.Rename {
+		err := rename.compile()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *NodeRules) Match(node *Adapter) bool {
+	if len(r.Include) > 0 {
+		var matched bool
+		for _, filter := range r.Include {
+			if filter.Match(node) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	for _, filter := range r.Exclude {
+		if filter.Match(node) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply 返回过滤并重命名后的节点，重命名不会修改传入的节点，{index} 为过滤后从 1 开始的序号
+func (r *NodeRules) Apply(nodes []*Adapter) ([]*Adapter, error) {
+	err := r.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*Adapter
+	for _, node := range nodes {
+		if !r.Match(node) {
+			continue
+		}
+
+		for _, rename := range r.Rename {
+			if name := rename.rename(node, len(list)+1); name != node.Name() {
+				node = node.WithName(name)
+			}
+		}
+
+		list = append(list, node)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,160 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestNodeRules(t *testing.T) {
+	nodes := adapter.ParseSubscription([]byte(`trojan://pwd@1.1.1.1:443#[A] 香港 01
+trojan://pwd@1.1.1.2:443#官网 example.com
+ss://YWVzLTEyOC1nY206cHdk@1.1.1.3:8388#[A] Japan Tokyo
+trojan://pwd@1.1.1.4:8443#[A] US Seattle
+trojan://pwd@1.1.1.5:443#剩余流量 100G traffic
+vless://7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d@1.1.1.6:443?security=tls&type=tcp#[A] 🇸🇬 Singapore
+`))
+
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "exclude",
+			yaml: `
+exclude:
+  - name: (?i)expire|traffic|官网
+`,
+			want: []string{"[A] 香港 01", "[A] Japan Tokyo", "[A] US Seattle", "[A] 🇸🇬 Singapore"},
+		},
+		{
+			name: "include region and type",
+			yaml: `
+include:
+  - region: HK|JP
+  - type: vless
+exclude:
+  - type: ^ss$
+`,
+			want: []string{"[A] 香港 01", "[A] 🇸🇬 Singapore"},
+		},
+		{
+			name: "include server and port",
+			yaml: `
+include:
+  - server: ^1\.1\.1\.[1-4]$
+    port: ^443$
+`,
+			want: []string{"[A] 香港 01", "官网 example.com"},
+		},
+		{
+			name: "rename",
+			yaml: `
+exclude:
+  - name: (?i)expire|traffic|官网
+rename:
+  - match: ^\[A\]\s*
+    replace: ""
+  - template: "{flag} {region}-{type}-{index}"
+`,
+			want: []string{"🇭🇰 香港-trojan-1", "🇯🇵 日本-ss-2", "🇺🇸 美国-trojan-3", "🇸🇬 新加坡-vless-4"},
+		},
+		{
+			name: "rename host",
+			yaml: `
+include:
+  - port: "8443"
+rename:
+  - template: "{code} {host}:{port} {name}"
+`,
+			want: []string{"US 1.1.1.4:8443 [A] US Seattle"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := adapter.ParseNodeRules([]byte(tt.yaml))
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			list, err := rules.Apply(nodes)
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			var names []string
+			for _, node := range list {
+				names = append(names, node.Name())
+			}
+
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("got %v\nwant %v", names, tt.want)
+			}
+		})
+	}
+
+	// 重命名不会修改原有的节点
+	if nodes[0].Name() != "[A] 香港 01" {
+		t.Errorf("source node renamed: %s", nodes[0].Name())
+	}
+
+	_, err := adapter.ParseNodeRules([]byte("include:\n  - name: \"(\"\n"))
+	if err == nil {
+		t.Errorf("want regexp error")
+	}
+
+	// 直接构造的规则在多个协程中使用
+	rules := &adapter.NodeRules{
+		Include: []*adapter.NodeFilter{{Name: "香港"}},
+		Rename:  []*adapter.NodeRename{{Match: `\[A\] `}},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			list, err := rules.Apply(nodes)
+			if err != nil || len(list) != 1 || list[0].Name() != "香港 01" {
+				t.Errorf("list = %v, err:%v", list, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDetectRegion(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "[A] 香港 01", want: "HK"},
+		{name: "HK-01", want: "HK"},
+		{name: "JP01 IPLC", want: "JP"},
+		{name: "🇸🇬 新加坡", want: "SG"},
+		{name: "united states", want: "US"},
+		{name: "Made in China"},
+		{name: "Plan 2 in use"},
+		{name: "ca-central"},
+		{name: "de facto"},
+		{name: "DE 01", want: "DE"},
+		{name: "CA 01", want: "CA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if region := adapter.DetectRegion(tt.name); region != nil {
+				got = region.Code
+			}
+
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/ice-cream-heaven/log"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type GroupType string
+
+const (
+	GroupSelect      GroupType = "select"
+	GroupURLTest     GroupType = "url-test"
+	GroupFallback    GroupType = "fallback"
+	GroupLoadBalance GroupType = "load-balance"
+)
+
+type LoadBalanceStrategy string
+
+const (
+	// LoadBalanceConsistentHashing 相同的目标域名使用相同的节点
+	LoadBalanceConsistentHashing LoadBalanceStrategy = "consistent-hashing"
+	LoadBalanceRoundRobin        LoadBalanceStrategy = "round-robin"
+)
+
+var ErrNodeNotFound = errors.New("node not found")
+
+// GroupOption 策略组的选项，与 clash 的 proxy-groups 对应
+type GroupOption struct {
+	Name string
+	Type GroupType
+
+	URL            string        // 默认为 DefaultClashTestURL
+	ExpectedStatus int           // 为 0 时 400 以下的状态码均视为成功
+	Timeout        time.Duration // 每个节点测速的超时时间，默认为 DefaultCheckTimeout
+	Concurrency    int           // 默认为 DefaultCheckConcurrency
+
+	// Interval 后台测速的间隔，为 0 时只在调用 Check 时测速
+	Interval time.Duration
+
+	// Tolerance url-test 中当前节点与最快的节点的延迟差在该范围内时不切换
+	Tolerance time.Duration
+
+	// Strategy load-balance 的策略，默认为 LoadBalanceConsistentHashing
+	Strategy LoadBalanceStrategy
+}
+
+// Group 策略组，与 Adapter 一样可以通过 HttpDialContext、Transport、GetClient、R 发起请求
+type Group struct {
+	opt     GroupOption
+	members []*Adapter
+
+	lock     sync.RWMutex
+	selected *Adapter
+	// 最近一次测速的结果，未测速时为 nil
+	alive   map[*Adapter]bool
+	latency map[*Adapter]time.Duration
+
+	roundRobin atomic.Uint64
+
+	client *resty.Client
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func NewGroup(opt GroupOption, members ...*Adapter) (*Group, error) {
+	if len(members) == 0 {
+		return nil, errors.New("group members is empty")
+	}
+
+	switch opt.Type {
+	case GroupSelect, GroupURLTest, GroupFallback:
+	case GroupLoadBalance:
+		switch opt.Strategy {
+		case "":
+			opt.Strategy = LoadBalanceConsistentHashing
+		case LoadBalanceConsistentHashing, LoadBalanceRoundRobin:
+		default:
+			return nil, fmt.Errorf("unsupported load balance strategy:%s", opt.Strategy)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported group type:%s", opt.Type)
+	}
+
+	if opt.URL == "" {
+		opt.URL = DefaultClashTestURL
+	}
+
+	g := &Group{
+		opt:      opt,
+		members:  members,
+		selected: members[0],
+	}
+
+	g.client = newRestyClient(g.Transport(), fmt.Sprintf("vanilla[%s]", opt.Name))
+
+	return g, nil
+}
+
+func (g *Group) Name() string {
+	return g.opt.Name
+}
+
+func (g *Group) Type() GroupType {
+	return g.opt.Type
+}
+
+func (g *Group) Members() []*Adapter {
+	return g.members
+}
+
+// Now 当前使用的节点，load-balance 会根据目标选择节点，返回第一个可用的节点
+func (g *Group) Now() *Adapter {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	switch g.opt.Type {
+	case GroupLoadBalance:
+		candidates := g.candidates()
+		return candidates[0]
+	default:
+		return g.selected
+	}
+}
+
+// Select 手动选择节点，只有 select 类型的策略组支持
+func (g *Group) Select(name string) error {
+	if g.opt.Type != GroupSelect {
+		return fmt.Errorf("group type %s does not support select", g.opt.Type)
+	}
+
+	for _, member := range g.members {
+		if member.Name() == name {
+			g.lock.Lock()
+			g.selected = member
+			g.lock.Unlock()
+			return nil
+		}
+	}
+
+	return ErrNodeNotFound
+}
+
+// Check 对所有节点测速并重新选择节点
+func (g *Group) Check(ctx context.Context) error {
+	results, err := CheckNodes(ctx, g.members, CheckOption{
+		URL:            g.opt.URL,
+		ExpectedStatus: g.opt.ExpectedStatus,
+		Concurrency:    g.opt.Concurrency,
+		Timeout:        g.opt.Timeout,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	alive := make(map[*Adapter]bool, len(results))
+	latency := make(map[*Adapter]time.Duration, len(results))
+	for _, result := range results {
+		alive[result.Node] = result.Ok()
+		if result.Ok() {
+			latency[result.Node] = result.Result.Total
+		}
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.alive = alive
+	g.latency = latency
+
+	switch g.opt.Type {
+	case GroupURLTest:
+		var fastest *Adapter
+		for _, member := range g.members {
+			if !alive[member] {
+				continue
+			}
+			if fastest == nil || latency[member] < latency[fastest] {
+				fastest = member
+			}
+		}
+
+		// 当前节点仍然可用且与最快的节点相差不大时不切换
+		switch {
+		case fastest == nil:
+		case alive[g.selected] && latency[g.selected]-latency[fastest] <= g.opt.Tolerance:
+		default:
+			g.selected = fastest
+		}
+
+	case GroupFallback:
+		for _, member := range g.members {
+			if alive[member] {
+				g.selected = member
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// Start 立即测速一次，并按照 Interval 在后台定时测速，Interval 为 0 时只测速一次
+func (g *Group) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	g.lock.Lock()
+	if g.cancel != nil {
+		g.lock.Unlock()
+		cancel()
+		return
+	}
+	g.cancel = cancel
+	g.done = make(chan struct{})
+	g.lock.Unlock()
+
+	go func() {
+		defer close(g.done)
+
+		check := func() {
+			err := g.Check(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Errorf("err:%v", err)
+			}
+		}
+
+		check()
+		if g.opt.Interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(g.opt.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台测速并等待正在进行的测速结束，停止后不能再次 Start
+func (g *Group) Stop() {
+	g.lock.RLock()
+	cancel, done := g.cancel, g.done
+	g.lock.RUnlock()
+
+	if cancel == nil {
+		return
+	}
+
+	g.stopOnce.Do(func() {
+		cancel()
+		<-done
+	})
+}
+
+// candidates 可用的节点，没有测速或者全部不可用时返回所有节点，调用前需要持有锁
+func (g *Group) candidates() []*Adapter {
+	if g.alive == nil {
+		return g.members
+	}
+
+	var list []*Adapter
+	for _, member := range g.members {
+		if g.alive[member] {
+			list = append(list, member)
+		}
+	}
+
+	if len(list) == 0 {
+		return g.members
+	}
+
+	return list
+}
+
+// pick 根据目标选择节点
+func (g *Group) pick(host string) *Adapter {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	if g.opt.Type != GroupLoadBalance {
+		return g.selected
+	}
+
+	candidates := g.candidates()
+
+	switch g.opt.Strategy {
+	case LoadBalanceRoundRobin:
+		return candidates[(g.roundRobin.Add(1)-1)%uint64(len(candidates))]
+
+	default:
+		// rendezvous hashing，节点增减时只影响对应节点上的目标
+		var best *Adapter
+		var bestScore uint64
+		for _, member := range candidates {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(host))
+			_, _ = h.Write([]byte(member.UniqueId()))
+			if score := h.Sum64(); best == nil || score > bestScore {
+				best, bestScore = member, score
+			}
+		}
+		return best
+	}
+}
+
+func (g *Group) HttpDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	return g.pick(host).HttpDialContext(ctx, network, addr)
+}
+
+func (g *Group) HttpDial(network, addr string) (net.Conn, error) {
+	return g.HttpDialContext(context.Background(), network, addr)
+}
+
+func (g *Group) Transport() http.RoundTripper {
+	return &http.Transport{
+		DialContext: g.HttpDialContext,
+	}
+}
+
+func (g *Group) GetClient() *http.Client {
+	return g.client.GetClient()
+}
+
+func (g *Group) GetClientWithTimeout(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: g.HttpDialContext,
+		},
+		Timeout: timeout,
+	}
+}
+
+func (g *Group) R() *resty.Request {
+	return g.client.R()
+}
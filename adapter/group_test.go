@@ -0,0 +1,185 @@
+package adapter_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	proxyA, proxyB := newSocks5Proxy(t), newSocks5Proxy(t)
+	a, b := proxyA.Adapter(t).WithName("a"), proxyB.Adapter(t).WithName("b")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	dead, err := adapter.ParseLink("socks5://" + l.Addr().String() + "#dead")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	_ = l.Close()
+
+	// 通过策略组发起请求，返回 a、b 新增的连接数
+	request := func(t *testing.T, g *adapter.Group, url string) (int32, int32) {
+		beforeA, beforeB := proxyA.conns.Load(), proxyB.conns.Load()
+
+		resp, err := g.GetClientWithTimeout(time.Second).Get(url)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		_ = resp.Body.Close()
+
+		return proxyA.conns.Load() - beforeA, proxyB.conns.Load() - beforeB
+	}
+
+	option := func(typ adapter.GroupType) adapter.GroupOption {
+		return adapter.GroupOption{
+			Name:    string(typ),
+			Type:    typ,
+			URL:     target.URL,
+			Timeout: time.Millisecond * 500,
+		}
+	}
+
+	t.Run("select", func(t *testing.T) {
+		g, err := adapter.NewGroup(option(adapter.GroupSelect), a, b)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		if ca, cb := request(t, g, target.URL); ca != 1 || cb != 0 {
+			t.Errorf("a:%d b:%d", ca, cb)
+		}
+
+		if err := g.Select("b"); err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		if ca, cb := request(t, g, target.URL); ca != 0 || cb != 1 || g.Now() != b {
+			t.Errorf("a:%d b:%d", ca, cb)
+		}
+
+		if err := g.Select("missing"); !errors.Is(err, adapter.ErrNodeNotFound) {
+			t.Errorf("err = %v", err)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		g, err := adapter.NewGroup(option(adapter.GroupFallback), dead, a, b)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		if err := g.Check(context.Background()); err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		if g.Now() != a {
+			t.Errorf("now = %v", g.Now().Name())
+		}
+
+		if err := g.Select("b"); err == nil {
+			t.Errorf("fallback should not support select")
+		}
+	})
+
+	t.Run("url-test", func(t *testing.T) {
+		proxyA.delay.Store(int64(time.Millisecond * 100))
+		defer proxyA.delay.Store(0)
+
+		opt := option(adapter.GroupURLTest)
+		g, err := adapter.NewGroup(opt, a, b)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		if err := g.Check(context.Background()); err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		if g.Now() != b {
+			t.Errorf("now = %v", g.Now().Name())
+		}
+
+		// 在容忍范围内时不切换
+		opt.Tolerance = time.Second
+		g, _ = adapter.NewGroup(opt, a, b)
+		_ = g.Check(context.Background())
+		if g.Now() != a {
+			t.Errorf("now = %v", g.Now().Name())
+		}
+	})
+
+	t.Run("load-balance", func(t *testing.T) {
+		g, err := adapter.NewGroup(option(adapter.GroupLoadBalance), a, b)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		first, _ := request(t, g, target.URL)
+		for i := 0; i < 5; i++ {
+			if ca, _ := request(t, g, target.URL); ca != first {
+				t.Errorf("consistent hashing should use the same node")
+			}
+		}
+
+		opt := option(adapter.GroupLoadBalance)
+		opt.Strategy = adapter.LoadBalanceRoundRobin
+		g, _ = adapter.NewGroup(opt, a, b)
+
+		var totalA, totalB int32
+		for i := 0; i < 4; i++ {
+			ca, cb := request(t, g, target.URL)
+			totalA, totalB = totalA+ca, totalB+cb
+		}
+		if totalA != 2 || totalB != 2 {
+			t.Errorf("a:%d b:%d", totalA, totalB)
+		}
+
+		// 不可用的节点不参与负载均衡
+		g, _ = adapter.NewGroup(opt, dead, a)
+		_ = g.Check(context.Background())
+		for i := 0; i < 2; i++ {
+			if ca, _ := request(t, g, target.URL); ca != 1 {
+				t.Errorf("dead node should be skipped")
+			}
+		}
+	})
+
+	t.Run("background", func(t *testing.T) {
+		opt := option(adapter.GroupFallback)
+		opt.Interval = time.Millisecond * 50
+
+		g, err := adapter.NewGroup(opt, a, b)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		g.Start()
+		time.Sleep(time.Millisecond * 180)
+		g.Stop()
+
+		checks := len(a.History().Results())
+		if checks < 2 {
+			t.Errorf("checks = %d", checks)
+		}
+
+		time.Sleep(time.Millisecond * 120)
+		if len(a.History().Results()) != checks {
+			t.Errorf("group should stop checking")
+		}
+	})
+
+	if _, err := adapter.NewGroup(adapter.GroupOption{Type: "unknown"}, a); err == nil {
+		t.Errorf("want error")
+	}
+}
@@ -0,0 +1,116 @@
+//go:build with_gvisor
+
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinkWireGuard(t *testing.T) {
+	tests := []struct {
+		name string
+
+		want map[string]any
+
+		wantErr bool
+	}{
+		{
+			name: "wireguard://CP3noqYXgzoT%2BaBUMoKWeXaRTXAoghWJxOJmflC9fd4%3D@1.2.3.4:2408?publickey=rAk8nY/ltaRfozOLDCSjeITJpe/JksaN3E0Q1MZjgm4=&reserved=1,2,3&address=172.16.0.2/32,fd01::2/128&mtu=1280#wg",
+			want: map[string]any{
+				"type":        "wireguard",
+				"server":      "1.2.3.4",
+				"port":        int64(2408),
+				"private-key": "CP3noqYXgzoT+aBUMoKWeXaRTXAoghWJxOJmflC9fd4=",
+				"public-key":  "rAk8nY/ltaRfozOLDCSjeITJpe/JksaN3E0Q1MZjgm4=",
+				"ip":          "172.16.0.2/32",
+				"ipv6":        "fd01::2/128",
+				"mtu":         int64(1280),
+			},
+		},
+		{
+			name: "wg://CP3noqYXgzoT%2BaBUMoKWeXaRTXAoghWJxOJmflC9fd4%3D@1.2.3.4:2408?publickey=rAk8nY/ltaRfozOLDCSjeITJpe/JksaN3E0Q1MZjgm4=&address=172.16.0.2",
+			want: map[string]any{
+				"type": "wireguard",
+				"ip":   "172.16.0.2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adapter.ParseLink(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			m := got.ToClash()
+			for key, value := range tt.want {
+				if !reflect.DeepEqual(m[key], value) {
+					t.Errorf("%s %s = %#v, want %#v", tt.name, key, m[key], value)
+				}
+			}
+
+			clash, err := adapter.ParseClash(m)
+			if err != nil {
+				t.Errorf("%v error = %v", m, err)
+				return
+			}
+
+			if clash.UniqueId() != got.UniqueId() {
+				t.Errorf("%v unique id mismatch", m)
+			}
+
+			again, err := adapter.ParseLink(got.ToV2ray())
+			if err != nil {
+				t.Errorf("%s error = %v", got.ToV2ray(), err)
+				return
+			}
+
+			if again.UniqueId() != got.UniqueId() {
+				t.Errorf("%s unique id mismatch", got.ToV2ray())
+			}
+		})
+	}
+}
+
+func TestParseSingBoxWireGuard(t *testing.T) {
+	nodes, err := adapter.ParseSingBox([]byte(`[{"type":"wireguard","tag":"wg","server":"1.2.3.4","server_port":2408,
+		"local_address":["172.16.0.2/32","fd01::2/128"],"private_key":"CP3noqYXgzoT+aBUMoKWeXaRTXAoghWJxOJmflC9fd4=",
+		"peer_public_key":"rAk8nY/ltaRfozOLDCSjeITJpe/JksaN3E0Q1MZjgm4=","reserved":[1,2,3],"mtu":1280}]`))
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	if len(nodes) != 1 {
+		t.Errorf("got %d nodes", len(nodes))
+		return
+	}
+
+	m := nodes[0].ToClash()
+	if m["ip"] != "172.16.0.2/32" || m["ipv6"] != "fd01::2/128" || m["public-key"] != "rAk8nY/ltaRfozOLDCSjeITJpe/JksaN3E0Q1MZjgm4=" {
+		t.Errorf("got %v", m)
+	}
+
+	// 与链接解析出的节点相同
+	link, err := adapter.ParseLink("wireguard://CP3noqYXgzoT%2BaBUMoKWeXaRTXAoghWJxOJmflC9fd4%3D@1.2.3.4:2408?publickey=rAk8nY/ltaRfozOLDCSjeITJpe/JksaN3E0Q1MZjgm4=&reserved=1,2,3&address=172.16.0.2/32,fd01::2/128&mtu=1280#wg")
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	if link.UniqueId() != nodes[0].UniqueId() {
+		t.Errorf("unique id mismatch")
+	}
+
+	outbounds := nodes[0].ToSingBox()
+	if len(outbounds) != 1 || !reflect.DeepEqual(outbounds[0]["local_address"], []any{"172.16.0.2/32", "fd01::2/128"}) {
+		t.Errorf("got %v", outbounds)
+	}
+}
@@ -0,0 +1,357 @@
+package adapter
+
+import (
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/anyx"
+	"github.com/metacubex/mihomo/adapter/outbound"
+	"github.com/metacubex/mihomo/common/structure"
+	"github.com/metacubex/mihomo/constant"
+	"strconv"
+	"strings"
+)
+
+// ParseLoon 解析 Loon 配置中 [Proxy] 的一行
+// https://nsloon.app/docs/Node/
+func ParseLoon(s string, opts ...ParseOption) (*Adapter, error) {
+	name, typ, server, port, fields, err := parseProxyName(s)
+	if err != nil {
+		return nil, err
+	}
+
+	args, params := parseFields(fields)
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	m := map[string]any{
+		"name":             name,
+		"server":           server,
+		"port":             port,
+		"udp":              params["udp"] == "true",
+		"skip-cert-verify": params["skip-cert-verify"] == "true",
+		"fingerprint":      params["tls-cert-sha256"],
+	}
+
+	switch typ {
+	case "shadowsocks":
+		m["type"] = "ss"
+		m["cipher"] = arg(0)
+		m["password"] = arg(1)
+
+		if params["obfs-name"] != "" {
+			m["plugin"] = "obfs"
+			m["plugin-opts"] = map[string]any{
+				"mode": params["obfs-name"],
+				"host": params["obfs-host"],
+			}
+		}
+
+	case "vmess":
+		m["type"] = "vmess"
+		m["cipher"] = arg(0)
+		m["uuid"] = arg(1)
+		m["alterId"] = anyx.ToInt(params["alterid"])
+		m["tls"] = params["over-tls"] == "true"
+		m["servername"] = params["tls-name"]
+
+		switch params["transport"] {
+		case "", "tcp":
+		case "ws":
+			m["network"] = "ws"
+			m["ws-opts"] = map[string]any{
+				"path":    params["path"],
+				"headers": map[string]any{"Host": params["host"]},
+			}
+		case "http":
+			m["network"] = "http"
+			m["http-opts"] = map[string]any{
+				"path":    []string{params["path"]},
+				"headers": map[string]any{"Host": []string{params["host"]}},
+			}
+		default:
+			log.Errorf("unsupported loon transport:%s", params["transport"])
+			return nil, ErrUnsupportedType
+		}
+
+	case "trojan":
+		m["type"] = "trojan"
+		m["password"] = arg(0)
+		m["sni"] = params["tls-name"]
+
+		switch params["transport"] {
+		case "", "tcp":
+		case "ws":
+			m["network"] = "ws"
+			m["ws-opts"] = map[string]any{
+				"path":    params["path"],
+				"headers": map[string]any{"Host": params["host"]},
+			}
+		default:
+			log.Errorf("unsupported loon transport:%s", params["transport"])
+			return nil, ErrUnsupportedType
+		}
+
+	case "http", "https":
+		m["type"] = "http"
+		m["tls"] = typ == "https"
+		m["sni"] = params["tls-name"]
+		m["username"] = arg(0)
+		m["password"] = arg(1)
+
+	case "socks5":
+		m["type"] = "socks5"
+		m["tls"] = params["over-tls"] == "true"
+		m["username"] = arg(0)
+		m["password"] = arg(1)
+
+	case "snell":
+		version, _ := strconv.Atoi(params["version"])
+
+		m["type"] = "snell"
+		m["psk"] = params["psk"]
+		m["version"] = version
+		// snell v1、v2 不支持 udp
+		m["udp"] = version >= 3
+
+		if params["obfs-name"] != "" {
+			m["obfs-opts"] = map[string]any{
+				"mode": params["obfs-name"],
+				"host": params["obfs-host"],
+			}
+		}
+
+	case "hysteria2":
+		m["type"] = "hysteria2"
+		m["password"] = arg(0)
+		m["sni"] = params["tls-name"]
+		m["down"] = params["download-bandwidth"]
+
+		if params["salamander-password"] != "" {
+			m["obfs"] = "salamander"
+			m["obfs-password"] = params["salamander-password"]
+		}
+
+	default:
+		log.Debugf("unsupported loon type:%s", typ)
+		return nil, ErrUnsupportedType
+	}
+
+	return ParseClash(compactClash(m), opts...)
+}
+
+// ToLoon 转换为 Loon 配置中 [Proxy] 的一行，不支持的协议返回空字符串
+func (p *Adapter) ToLoon() string {
+	var fields []string
+	set := func(key, value string) {
+		if value != "" {
+			fields = append(fields, key+"="+value)
+		}
+	}
+	setBool := func(key string, value bool) {
+		if value {
+			fields = append(fields, key+"=true")
+		}
+	}
+	quote := func(value string) string {
+		return `"` + value + `"`
+	}
+
+	switch p.Type() {
+	case constant.Shadowsocks:
+		var opt *outbound.ShadowSocksOption
+		switch x := p.clashOpt.(type) {
+		case outbound.ShadowSocksOption:
+			opt = &x
+		case *outbound.ShadowSocksOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "Shadowsocks", opt.Server, strconv.Itoa(opt.Port), opt.Cipher, quote(opt.Password))
+
+		switch opt.Plugin {
+		case "":
+		case "obfs":
+			decoder := structure.NewDecoder(structure.Option{TagName: "obfs", WeaklyTypedInput: true})
+
+			o := simpleObfsOption{Host: "bing.com"}
+			err := decoder.Decode(opt.PluginOpts, &o)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return ""
+			}
+
+			set("obfs-name", o.Mode)
+			set("obfs-host", o.Host)
+
+		default:
+			return ""
+		}
+
+		setBool("udp", opt.UDP)
+
+	case constant.Vmess:
+		var opt *outbound.VmessOption
+		switch x := p.clashOpt.(type) {
+		case outbound.VmessOption:
+			opt = &x
+		case *outbound.VmessOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "vmess", opt.Server, strconv.Itoa(opt.Port), opt.Cipher, quote(opt.UUID))
+
+		switch opt.Network {
+		case "", "tcp":
+			set("transport", "tcp")
+		case "ws":
+			set("transport", "ws")
+			set("path", opt.WSOpts.Path)
+			set("host", opt.WSOpts.Headers["Host"])
+		case "http":
+			set("transport", "http")
+			if len(opt.HTTPOpts.Path) > 0 {
+				set("path", opt.HTTPOpts.Path[0])
+			}
+			if len(opt.HTTPOpts.Headers["Host"]) > 0 {
+				set("host", opt.HTTPOpts.Headers["Host"][0])
+			}
+		default:
+			return ""
+		}
+
+		set("alterId", strconv.Itoa(opt.AlterID))
+		setBool("over-tls", opt.TLS)
+		set("tls-name", opt.ServerName)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+		setBool("udp", opt.UDP)
+
+	case constant.Trojan:
+		var opt *outbound.TrojanOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TrojanOption:
+			opt = &x
+		case *outbound.TrojanOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		if opt.RealityOpts.PublicKey != "" {
+			return ""
+		}
+
+		fields = append(fields, "trojan", opt.Server, strconv.Itoa(opt.Port), quote(opt.Password))
+		set("tls-name", opt.SNI)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+
+		switch opt.Network {
+		case "", "tcp":
+		case "ws":
+			set("transport", "ws")
+			set("path", opt.WSOpts.Path)
+			set("host", opt.WSOpts.Headers["Host"])
+		default:
+			return ""
+		}
+
+		setBool("udp", opt.UDP)
+
+	case constant.Http:
+		var opt *outbound.HttpOption
+		switch x := p.clashOpt.(type) {
+		case outbound.HttpOption:
+			opt = &x
+		case *outbound.HttpOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		typ := "http"
+		if opt.TLS {
+			typ = "https"
+		}
+
+		fields = append(fields, typ, opt.Server, strconv.Itoa(opt.Port))
+		if opt.UserName != "" || opt.Password != "" {
+			fields = append(fields, opt.UserName, quote(opt.Password))
+		}
+		set("tls-name", opt.SNI)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+
+	case constant.Socks5:
+		var opt *outbound.Socks5Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Socks5Option:
+			opt = &x
+		case *outbound.Socks5Option:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "socks5", opt.Server, strconv.Itoa(opt.Port))
+		if opt.UserName != "" || opt.Password != "" {
+			fields = append(fields, opt.UserName, quote(opt.Password))
+		}
+		setBool("over-tls", opt.TLS)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+		setBool("udp", opt.UDP)
+
+	case constant.Snell:
+		var opt *outbound.SnellOption
+		switch x := p.clashOpt.(type) {
+		case outbound.SnellOption:
+			opt = &x
+		case *outbound.SnellOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "Snell", opt.Server, strconv.Itoa(opt.Port))
+		set("psk", opt.Psk)
+		if opt.Version != 0 {
+			set("version", strconv.Itoa(opt.Version))
+		}
+		set("obfs-name", anyx.ToString(opt.ObfsOpts["mode"]))
+		set("obfs-host", anyx.ToString(opt.ObfsOpts["host"]))
+
+	case constant.Hysteria2:
+		var opt *outbound.Hysteria2Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Hysteria2Option:
+			opt = &x
+		case *outbound.Hysteria2Option:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "Hysteria2", opt.Server, strconv.Itoa(opt.Port), quote(opt.Password))
+		set("tls-name", opt.SNI)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+		if opt.Obfs == "salamander" {
+			set("salamander-password", opt.ObfsPassword)
+		} else if opt.Obfs != "" {
+			return ""
+		}
+		if down := outbound.StringToBps(opt.Down); down != 0 {
+			set("download-bandwidth", strconv.FormatUint(down/125000, 10))
+		}
+
+	default:
+		return ""
+	}
+
+	set("tls-cert-sha256", anyx.ToString(p.opt["fingerprint"]))
+
+	return p.Name() + " = " + strings.Join(fields, ",")
+}
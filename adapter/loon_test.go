@@ -0,0 +1,76 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"testing"
+)
+
+func TestParseLoon(t *testing.T) {
+	tests := []struct {
+		name string
+		want map[string]any
+	}{
+		{
+			name: `ss = Shadowsocks,1.1.1.1,8388,aes-128-gcm,"pwd,=",obfs-name=http,obfs-host=bing.com,udp=true`,
+			want: map[string]any{"type": "ss", "cipher": "aes-128-gcm", "password": "pwd,=", "udp": true, "plugin": "obfs",
+				"plugin-opts": map[string]any{"mode": "http", "host": "bing.com"}},
+		},
+		{
+			name: `vmess = vmess,1.1.1.1,443,auto,"7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d",transport=ws,path=/ws,host=example.com,alterId=0,over-tls=true,tls-name=example.com`,
+			want: map[string]any{"type": "vmess", "uuid": "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d", "cipher": "auto", "alterId": 0, "tls": true, "servername": "example.com",
+				"network": "ws", "ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "example.com"}}},
+		},
+		{
+			name: `trojan = trojan,1.1.1.1,443,"pwd",tls-name=example.com,skip-cert-verify=true,udp=true`,
+			want: map[string]any{"type": "trojan", "password": "pwd", "sni": "example.com", "skip-cert-verify": true, "udp": true},
+		},
+		{
+			name: `http = http,1.1.1.1,8080,user,"pwd"`,
+			want: map[string]any{"type": "http", "username": "user", "password": "pwd"},
+		},
+		{
+			name: `socks5 = socks5,1.1.1.1,1080,user,"pwd",over-tls=true,udp=true`,
+			want: map[string]any{"type": "socks5", "tls": true, "username": "user", "password": "pwd", "udp": true},
+		},
+		{
+			name: `snell = Snell,1.1.1.1,443,psk=psk,version=2,obfs-name=tls,obfs-host=bing.com`,
+			want: map[string]any{"type": "snell", "psk": "psk", "version": 2, "obfs-opts": map[string]any{"mode": "tls", "host": "bing.com"}},
+		},
+		{
+			name: `hy2 = Hysteria2,1.1.1.1,443,"pwd",tls-name=example.com,salamander-password=obfs,download-bandwidth=100`,
+			want: map[string]any{"type": "hysteria2", "password": "pwd", "sni": "example.com", "obfs": "salamander", "obfs-password": "obfs", "down": "100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adapter.ParseLoon(tt.name)
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			m := got.ToClash()
+			for key, value := range tt.want {
+				if !reflect.DeepEqual(m[key], value) {
+					t.Errorf("%s = %#v, want %#v", key, m[key], value)
+				}
+			}
+
+			again, err := adapter.ParseLoon(got.ToLoon())
+			if err != nil {
+				t.Errorf("%s error = %v", got.ToLoon(), err)
+				return
+			}
+
+			if again.UniqueId() != got.UniqueId() || again.Name() != got.Name() {
+				t.Errorf("%s unique id mismatch", got.ToLoon())
+			}
+
+			detected, err := adapter.ParseProxyLine(tt.name)
+			if err != nil || detected.UniqueId() != got.UniqueId() {
+				t.Errorf("detect err:%v", err)
+			}
+		})
+	}
+}
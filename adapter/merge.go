@@ -0,0 +1,127 @@
+package adapter
+
+import (
+	"github.com/elliotchance/pie/v2"
+	"reflect"
+	"strconv"
+)
+
+// MergeSource 一个已经解析的订阅来源
+type MergeSource struct {
+	Name  string
+	Nodes []*Adapter
+}
+
+// MergedNode 合并后的节点，名称已经去重
+type MergedNode struct {
+	*Adapter
+
+	// Sources 节点出现过的所有来源，按来源的顺序排列
+	Sources []string
+}
+
+type MergeResult struct {
+	Nodes []*MergedNode
+
+	index map[string]*MergedNode
+}
+
+// MergeDiff 两次合并之间的差异，Changed 为 unique_id 相同但名称、来源或配置发生变化的节点
+type MergeDiff struct {
+	Added   []*MergedNode
+	Removed []*MergedNode
+	Changed []*MergedNode
+}
+
+// Merge 按 unique_id 合并多个来源的节点，重复的节点保留第一次出现的配置。
+// 名称冲突时按出现顺序在后面追加编号，如 "香港"、"香港 2"、"香港 3"
+func Merge(sources ...*MergeSource) *MergeResult {
+	r := &MergeResult{
+		index: map[string]*MergedNode{},
+	}
+
+	for _, source := range sources {
+		for _, node := range source.Nodes {
+			if merged, ok := r.index[node.UniqueId()]; ok {
+				if !pie.Contains(merged.Sources, source.Name) {
+					merged.Sources = append(merged.Sources, source.Name)
+				}
+				continue
+			}
+
+			merged := &MergedNode{
+				Adapter: node,
+				Sources: []string{source.Name},
+			}
+			r.index[node.UniqueId()] = merged
+			r.Nodes = append(r.Nodes, merged)
+		}
+	}
+
+	names := make(map[string]bool, len(r.Nodes))
+	for _, merged := range r.Nodes {
+		names[merged.Name()] = true
+	}
+
+	used := make(map[string]bool, len(r.Nodes))
+	for _, merged := range r.Nodes {
+		name := merged.Name()
+		if used[name] {
+			// 跳过已经存在的名称，避免 "香港 2" 与原有的节点冲突
+			for i := 2; ; i++ {
+				candidate := name + " " + strconv.Itoa(i)
+				if !used[candidate] && !names[candidate] {
+					name = candidate
+					break
+				}
+			}
+			merged.Adapter = merged.Adapter.WithName(name)
+		}
+		used[name] = true
+	}
+
+	return r
+}
+
+func (r *MergeResult) Adapters() []*Adapter {
+	return pie.Map(r.Nodes, func(node *MergedNode) *Adapter {
+		return node.Adapter
+	})
+}
+
+func (r *MergeResult) Get(uniqueId string) (*MergedNode, bool) {
+	node, ok := r.index[uniqueId]
+	return node, ok
+}
+
+// Diff 对比上一次的合并结果，prev 为 nil 时所有节点都是新增
+func (r *MergeResult) Diff(prev *MergeResult) *MergeDiff {
+	diff := &MergeDiff{}
+
+	for _, node := range r.Nodes {
+		if prev == nil {
+			diff.Added = append(diff.Added, node)
+			continue
+		}
+
+		old, ok := prev.index[node.UniqueId()]
+		if !ok {
+			diff.Added = append(diff.Added, node)
+			continue
+		}
+
+		if !reflect.DeepEqual(old.Sources, node.Sources) || !reflect.DeepEqual(old.ToClash(), node.ToClash()) {
+			diff.Changed = append(diff.Changed, node)
+		}
+	}
+
+	if prev != nil {
+		for _, node := range prev.Nodes {
+			if _, ok := r.index[node.UniqueId()]; !ok {
+				diff.Removed = append(diff.Removed, node)
+			}
+		}
+	}
+
+	return diff
+}
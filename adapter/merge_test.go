@@ -0,0 +1,103 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	parse := func(links ...string) []*adapter.Adapter {
+		var nodes []*adapter.Adapter
+		for _, link := range links {
+			node, err := adapter.ParseLink(link)
+			if err != nil {
+				t.Fatalf("err:%v", err)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	a := &adapter.MergeSource{
+		Name: "a",
+		Nodes: parse(
+			"trojan://pwd@1.1.1.1:443#香港",
+			"trojan://pwd@2.2.2.2:443#香港",
+			"ss://YWVzLTEyOC1nY206cHdk@3.3.3.3:8388#香港 2",
+		),
+	}
+
+	// 同一个节点的 clash 格式，名称不同
+	b := &adapter.MergeSource{Name: "b"}
+	for _, yaml := range []string{
+		"{name: hk, type: trojan, server: 1.1.1.1, port: 443, password: pwd}",
+		"{name: 香港, type: trojan, server: 4.4.4.4, port: 443, password: pwd}",
+	} {
+		node, err := adapter.ParseClashWithYaml([]byte(yaml))
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		b.Nodes = append(b.Nodes, node)
+	}
+
+	first := adapter.Merge(a, b)
+
+	var names []string
+	var sources [][]string
+	for _, node := range first.Nodes {
+		names = append(names, node.Name())
+		sources = append(sources, node.Sources)
+	}
+
+	if !reflect.DeepEqual(names, []string{"香港", "香港 3", "香港 2", "香港 4"}) {
+		t.Errorf("names = %v", names)
+	}
+
+	if !reflect.DeepEqual(sources, [][]string{{"a", "b"}, {"a"}, {"a"}, {"b"}}) {
+		t.Errorf("sources = %v", sources)
+	}
+
+	// 重命名不影响原节点
+	if a.Nodes[1].Name() != "香港" || first.Nodes[1].UniqueId() != a.Nodes[1].UniqueId() {
+		t.Errorf("source node renamed")
+	}
+
+	if first.Nodes[1].ToClash()["name"] != "香港 3" {
+		t.Errorf("clash name = %v", first.Nodes[1].ToClash()["name"])
+	}
+
+	diff := first.Diff(nil)
+	if len(diff.Added) != 4 || len(diff.Removed) != 0 || len(diff.Changed) != 0 {
+		t.Errorf("diff = %+v", diff)
+	}
+
+	// 第二次：b 不再提供 1.1.1.1，4.4.4.4 下线，新增 5.5.5.5
+	b.Nodes = parse("trojan://pwd@5.5.5.5:443#新加坡")
+
+	second := adapter.Merge(a, b)
+	diff = second.Diff(first)
+
+	ids := func(nodes []*adapter.MergedNode) (list []string) {
+		for _, node := range nodes {
+			list = append(list, node.Name())
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(ids(diff.Added), []string{"新加坡"}) {
+		t.Errorf("added = %v", ids(diff.Added))
+	}
+
+	if !reflect.DeepEqual(ids(diff.Removed), []string{"香港 4"}) {
+		t.Errorf("removed = %v", ids(diff.Removed))
+	}
+
+	if !reflect.DeepEqual(ids(diff.Changed), []string{"香港"}) {
+		t.Errorf("changed = %v", ids(diff.Changed))
+	}
+
+	if node, ok := second.Get(a.Nodes[0].UniqueId()); !ok || !reflect.DeepEqual(node.Sources, []string{"a"}) {
+		t.Errorf("get = %v", node)
+	}
+}
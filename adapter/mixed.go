@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"strings"
+)
+
+// mixedLineResult 一行中解析出的一个节点，base64 编码的行可能包含多个节点
+type mixedLineResult struct {
+	node *Adapter
+	raw  string
+	err  error
+}
+
+func isCommentLine(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
+
+// parseMixedLine 按行识别格式：链接、base64 编码的链接、json 或 yaml 的 clash 节点，注释与空行返回 nil
+func parseMixedLine(line string, opts ...ParseOption) []*mixedLineResult {
+	line = strings.TrimSpace(line)
+	if line == "" || isCommentLine(line) {
+		return nil
+	}
+
+	// NOTE: 链接
+	if strings.Contains(line, "://") {
+		node, err := ParseLink(line, opts...)
+		return []*mixedLineResult{{node: node, raw: line, err: err}}
+	}
+
+	// NOTE: clash 节点，如 {"name":"a","type":"ss"} 或 - {name: a, type: ss}
+	if m := strings.TrimSpace(strings.TrimPrefix(line, "- ")); strings.HasPrefix(m, "{") && strings.HasSuffix(m, "}") {
+		node, err := ParseClashWithYaml([]byte(m), opts...)
+		return []*mixedLineResult{{node: node, raw: line, err: err}}
+	}
+
+	// NOTE: base64 编码的链接，可能包含多行
+	if decoded := Base64Decode(line); decoded != line && strings.Contains(decoded, "://") {
+		var results []*mixedLineResult
+		for _, sub := range strings.Split(decoded, "\n") {
+			sub = strings.TrimSpace(sub)
+			if sub == "" || isCommentLine(sub) {
+				continue
+			}
+
+			node, err := ParseLink(sub, opts...)
+			results = append(results, &mixedLineResult{node: node, raw: sub, err: err})
+		}
+		return results
+	}
+
+	return []*mixedLineResult{{raw: line, err: ErrUnsupportedType}}
+}
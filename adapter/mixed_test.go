@@ -0,0 +1,51 @@
+package adapter_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"strings"
+	"testing"
+)
+
+func TestParseSubscriptionMixed(t *testing.T) {
+	body := strings.Join([]string{
+		"# 机场节点",
+		"trojan://pwd@1.1.1.1:443#trojan",
+		"",
+		base64.StdEncoding.EncodeToString([]byte("ss://YWVzLTEyOC1nY206cHdk@1.1.1.2:8388#ss")),
+		base64.RawURLEncoding.EncodeToString([]byte("trojan://pwd@1.1.1.3:443#b1\ntrojan://pwd@1.1.1.4:443#b2")),
+		`{"name":"json","type":"trojan","server":"1.1.1.5","port":443,"password":"pwd"}`,
+		"  - {name: yaml, type: trojan, server: 1.1.1.6, port: 443, password: pwd}",
+		"// comment",
+		"not a node",
+		"- {name: broken, type: trojan, server: 1.1.1.7}",
+	}, "\n")
+
+	report := adapter.ParseSubscriptionReport([]byte(body))
+	if report.Format != adapter.SubscriptionFormatLink {
+		t.Errorf("format = %v", report.Format)
+	}
+
+	var names []string
+	for _, node := range report.Adapters {
+		names = append(names, node.Name())
+	}
+	if strings.Join(names, ",") != "trojan,ss,b1,b2,json,yaml" {
+		t.Errorf("names = %v", names)
+	}
+
+	var rejections []string
+	for _, rejection := range report.Rejections {
+		rejections = append(rejections, fmt.Sprintf("%d:%v", rejection.Line, rejection.Kind))
+	}
+	if strings.Join(rejections, ",") != "9:unsupported type,10:invalid field" {
+		t.Errorf("rejections = %v", rejections)
+	}
+
+	// 流式解析的结果一致
+	streamNames, lines := collectStream(t, strings.NewReader(body))
+	if strings.Join(streamNames, ",") != strings.Join(names, ",") || fmt.Sprint(lines) != "[9 10]" {
+		t.Errorf("stream names:%v lines:%v", streamNames, lines)
+	}
+}
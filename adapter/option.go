@@ -0,0 +1,58 @@
+package adapter
+
+import "context"
+
+// ParseOption 解析链接或订阅时的额外配置
+type ParseOption struct {
+	TlsVerify TlsVerify
+
+	// CustomCA PEM 格式的证书，只对解析出的节点生效。
+	// hysteria、hysteria2、tuic 会写入节点的 ca-str；
+	// 其余协议 mihomo 不支持单独配置 CA，并不是真正的 CA 证书包：只能包含一个证书并转换为证书指纹，
+	// 要求服务端在证书链中发送该证书（如自签名证书），仅由该证书签发的证书不会被信任。
+	// REALITY 节点不校验证书，会被忽略
+	CustomCA string
+
+	// Fingerprint 证书的 sha256 指纹，设置后只信任该证书
+	Fingerprint string
+
+	// BaseDir 设置后才会读取 file 类型的 proxy-providers 以及 http 类型的本地缓存，
+	// 路径只能是该目录内的相对路径
+	BaseDir string
+
+	// Fetcher 设置后才会拉取 http 类型的 proxy-providers，可以使用 FetchProvider
+	Fetcher ProviderFetcher
+
+	// Decoders 解析订阅前对内容依次解码，为空时使用 DefaultPayloadDecoders
+	Decoders []PayloadDecoder
+
+	// MaxPayloadSize 解压后订阅内容的最大字节数，默认为 DefaultMaxPayloadSize。
+	// 自定义 Decoders 时需要使用 NewGzipDecoder 等指定上限
+	MaxPayloadSize int64
+
+	// Decryption 订阅内容的加密方式，设置后会尝试解密
+	Decryption *PayloadCipher
+
+	// Workers ParseSubscriptionStream 创建节点的并发数，默认为 CPU 核数
+	Workers int
+
+	// 解析 proxy-providers 的内容时不再展开其中的 proxy-providers
+	nested bool
+
+	// 拉取 proxy-providers 时使用的 context，由 ParseSubscriptionReportContext 等设置
+	ctx context.Context
+}
+
+func newParseOption(opts ...ParseOption) ParseOption {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return ParseOption{}
+}
+
+func (o ParseOption) context() context.Context {
+	if o.ctx != nil {
+		return o.ctx
+	}
+	return context.Background()
+}
@@ -16,6 +16,7 @@ var (
 	ErrEmptyDate       = errors.New("empty date")
 
 	ErrParseLink = errors.New("parse link error")
+	ErrNewProxy  = errors.New("new proxy error")
 
 	decoder = structure.NewDecoder(
 		structure.Option{
@@ -25,20 +26,21 @@ var (
 	)
 )
 
-func ParseClash(m map[string]any) (*Adapter, error) {
+func ParseClash(m map[string]any, opts ...ParseOption) (*Adapter, error) {
 	if _, ok := m["name"]; !ok {
 		m["name"] = app.Name
 	}
 
-	if typ, ok := m["type"]; ok {
-		if t, ok := typ.(string); ok {
-			switch t {
-			case "shadowsocks":
-				m["type"] = "ss"
-			case "shadowsocksr":
-				m["type"] = "ssr"
-			}
-		}
+	err := ValidateClash(m)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	err = newParseOption(opts...).apply(m)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
 	}
 
 	p, err := adapter.ParseProxy(m)
@@ -54,7 +56,23 @@ func ParseClash(m map[string]any) (*Adapter, error) {
 	return NewAdapter(p, m)
 }
 
-func ParseClashWithJson(s []byte) (*Adapter, error) {
+// compactClash 去掉未设置的字段，保持与 clash 配置一致，vmess 的 alterId 为必填
+func compactClash(m map[string]any) map[string]any {
+	for key, value := range m {
+		if key == "alterId" {
+			continue
+		}
+
+		switch value {
+		case "", 0, false:
+			delete(m, key)
+		}
+	}
+
+	return m
+}
+
+func ParseClashWithJson(s []byte, opts ...ParseOption) (*Adapter, error) {
 	var m map[string]any
 	err := json.Unmarshal(s, &m)
 	if err != nil {
@@ -62,10 +80,10 @@ func ParseClashWithJson(s []byte) (*Adapter, error) {
 		return nil, err
 	}
 
-	return ParseClash(m)
+	return ParseClash(m, opts...)
 }
 
-func ParseClashWithYaml(s []byte) (*Adapter, error) {
+func ParseClashWithYaml(s []byte, opts ...ParseOption) (*Adapter, error) {
 	var m map[string]any
 	err := yaml.Unmarshal(s, &m)
 	if err != nil {
@@ -73,5 +91,5 @@ func ParseClashWithYaml(s []byte) (*Adapter, error) {
 		return nil, err
 	}
 
-	return ParseClash(m)
+	return ParseClash(m, opts...)
 }
@@ -0,0 +1,343 @@
+package adapter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/json"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// 解码的最大轮数，避免解码器互相嵌套时死循环
+const maxPayloadDecodeRounds = 8
+
+// DefaultMaxPayloadSize 解压后订阅内容的默认最大字节数
+const DefaultMaxPayloadSize = 64 * 1024 * 1024
+
+var (
+	ErrDecryptPayload  = errors.New("decrypt payload error")
+	ErrPayloadTooLarge = errors.New("payload too large")
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// PayloadDecoder 对订阅内容做一层解码，无法识别时返回 ok 为 false，
+// 识别出格式但解码失败时返回错误
+type PayloadDecoder func(b []byte) (out []byte, ok bool, err error)
+
+// DefaultPayloadDecoders 默认的解码器，自定义时可以在此基础上追加
+var DefaultPayloadDecoders = []PayloadDecoder{
+	GzipDecoder,
+	ZstdDecoder,
+	BrotliDecoder,
+	JsonEnvelopeDecoder,
+}
+
+// PayloadCipher 订阅内容的加密方式，密文可以是原始字节或 base64 编码
+type PayloadCipher struct {
+	// Mode 为 gcm 或 cbc
+	Mode string
+	// Key 长度为 16、24 或 32 字节
+	Key []byte
+	// IV cbc 使用的 iv，为空时取密文的前 16 字节。gcm 固定取密文的前 12 字节作为 nonce
+	IV []byte
+}
+
+// DecodePayload 依次尝试 ParseOption.Decoders 中的解码器，直到没有解码器可以识别，
+// 配置了 ParseOption.Decryption 时会在最后尝试解密。base64 留给具体的格式处理
+func DecodePayload(b []byte, opts ...ParseOption) ([]byte, error) {
+	opt := newParseOption(opts...)
+
+	decoders := opt.Decoders
+	if decoders == nil {
+		decoders = DefaultPayloadDecoders
+		if opt.MaxPayloadSize > 0 {
+			decoders = []PayloadDecoder{
+				NewGzipDecoder(opt.MaxPayloadSize),
+				NewZstdDecoder(opt.MaxPayloadSize),
+				NewBrotliDecoder(opt.MaxPayloadSize),
+				JsonEnvelopeDecoder,
+			}
+		}
+	}
+
+	if opt.Decryption != nil {
+		decoder, err := NewCipherDecoder(*opt.Decryption)
+		if err != nil {
+			return nil, err
+		}
+		decoders = append(append([]PayloadDecoder{}, decoders...), decoder)
+	}
+
+	for round := 0; round < maxPayloadDecodeRounds; round++ {
+		var decoded bool
+		for _, decoder := range decoders {
+			out, ok, err := decoder(b)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+
+			if ok {
+				b, decoded = out, true
+				break
+			}
+		}
+
+		if !decoded {
+			break
+		}
+	}
+
+	return b, nil
+}
+
+// payloadLimitReader 读取超过 n 个字节时返回 ErrPayloadTooLarge，避免解压炸弹
+type payloadLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func newPayloadLimitReader(r io.Reader, maxSize int64) *payloadLimitReader {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxPayloadSize
+	}
+	return &payloadLimitReader{r: r, n: maxSize}
+}
+
+func (l *payloadLimitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrPayloadTooLarge
+	}
+
+	// 多读一个字节，用于判断是否超出
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n - 1, ErrPayloadTooLarge
+	}
+
+	// zstd 超过 WithDecoderMaxMemory 时返回自己的错误
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		return n, ErrPayloadTooLarge
+	}
+
+	return n, err
+}
+
+func GzipDecoder(b []byte) ([]byte, bool, error) {
+	return NewGzipDecoder(DefaultMaxPayloadSize)(b)
+}
+
+// NewGzipDecoder 解压后超过 maxSize 字节时返回 ErrPayloadTooLarge
+func NewGzipDecoder(maxSize int64) PayloadDecoder {
+	return func(b []byte) ([]byte, bool, error) {
+		if !bytes.HasPrefix(b, gzipMagic) {
+			return nil, false, nil
+		}
+
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, true, err
+		}
+		defer r.Close()
+
+		out, err := io.ReadAll(newPayloadLimitReader(r, maxSize))
+		if err != nil {
+			return nil, true, err
+		}
+
+		return out, true, nil
+	}
+}
+
+func ZstdDecoder(b []byte) ([]byte, bool, error) {
+	return NewZstdDecoder(DefaultMaxPayloadSize)(b)
+}
+
+// NewZstdDecoder 解压后超过 maxSize 字节时返回 ErrPayloadTooLarge
+func NewZstdDecoder(maxSize int64) PayloadDecoder {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxPayloadSize
+	}
+
+	return func(b []byte) ([]byte, bool, error) {
+		if !bytes.HasPrefix(b, zstdMagic) {
+			return nil, false, nil
+		}
+
+		r, err := zstd.NewReader(bytes.NewReader(b), zstd.WithDecoderMaxMemory(uint64(maxSize)))
+		if err != nil {
+			return nil, true, err
+		}
+		defer r.Close()
+
+		out, err := io.ReadAll(newPayloadLimitReader(r, maxSize))
+		if err != nil {
+			return nil, true, err
+		}
+
+		return out, true, nil
+	}
+}
+
+// BrotliDecoder brotli 没有 magic，只对非文本的内容尝试解码，解码结果为文本时视为识别成功
+func BrotliDecoder(b []byte) ([]byte, bool, error) {
+	return NewBrotliDecoder(DefaultMaxPayloadSize)(b)
+}
+
+// NewBrotliDecoder 解压后超过 maxSize 字节时返回 ErrPayloadTooLarge
+func NewBrotliDecoder(maxSize int64) PayloadDecoder {
+	return func(b []byte) ([]byte, bool, error) {
+		if len(b) == 0 || utf8.Valid(b) {
+			return nil, false, nil
+		}
+
+		out, err := io.ReadAll(newPayloadLimitReader(brotli.NewReader(bytes.NewReader(b)), maxSize))
+		if errors.Is(err, ErrPayloadTooLarge) {
+			return nil, true, err
+		}
+
+		if err != nil || len(out) == 0 || !utf8.Valid(out) {
+			return nil, false, nil
+		}
+
+		return out, true, nil
+	}
+}
+
+// JsonEnvelopeDecoder 解析 {"data":"..."} 形式的包装，支持 data、content、subscription、sub、body、result，
+// 可以嵌套一层对象，如 {"data":{"content":"..."}}
+func JsonEnvelopeDecoder(b []byte) ([]byte, bool, error) {
+	trimmed := bytes.TrimSpace(b)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		return nil, false, nil
+	}
+
+	var m map[string]any
+	err := json.Unmarshal(trimmed, &m)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	if s, ok := jsonEnvelopeValue(m, 2); ok {
+		return []byte(s), true, nil
+	}
+
+	return nil, false, nil
+}
+
+func jsonEnvelopeValue(m map[string]any, depth int) (string, bool) {
+	// sing-box、xray、SIP008 等配置不是包装
+	for _, key := range []string{"outbounds", "servers", "proxies"} {
+		if _, ok := m[key]; ok {
+			return "", false
+		}
+	}
+
+	for _, key := range []string{"data", "content", "subscription", "sub", "body", "result"} {
+		switch x := m[key].(type) {
+		case string:
+			if strings.TrimSpace(x) != "" {
+				return x, true
+			}
+		case map[string]any:
+			if depth > 1 {
+				if s, ok := jsonEnvelopeValue(x, depth-1); ok {
+					return s, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// NewCipherDecoder 返回使用 AES-GCM 或 AES-CBC 解密的解码器，解密失败时视为无法识别
+func NewCipherDecoder(c PayloadCipher) (PayloadDecoder, error) {
+	block, err := aes.NewCipher(c.Key)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	var decrypt func(b []byte) ([]byte, error)
+	switch strings.ToLower(c.Mode) {
+	case "gcm", "":
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		decrypt = func(b []byte) ([]byte, error) {
+			if len(b) < gcm.NonceSize()+gcm.Overhead() {
+				return nil, ErrDecryptPayload
+			}
+			return gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+		}
+
+	case "cbc":
+		if c.IV != nil && len(c.IV) != aes.BlockSize {
+			return nil, fmt.Errorf("invalid iv length:%d", len(c.IV))
+		}
+
+		decrypt = func(b []byte) ([]byte, error) {
+			iv := c.IV
+			if iv == nil {
+				if len(b) < aes.BlockSize {
+					return nil, ErrDecryptPayload
+				}
+				iv, b = b[:aes.BlockSize], b[aes.BlockSize:]
+			}
+
+			if len(b) == 0 || len(b)%aes.BlockSize != 0 {
+				return nil, ErrDecryptPayload
+			}
+
+			out := make([]byte, len(b))
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, b)
+
+			// PKCS#7
+			padding := int(out[len(out)-1])
+			if padding == 0 || padding > aes.BlockSize || !bytes.Equal(out[len(out)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+				return nil, ErrDecryptPayload
+			}
+			return out[:len(out)-padding], nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported cipher mode:%s", c.Mode)
+	}
+
+	return func(b []byte) ([]byte, bool, error) {
+		candidates := [][]byte{b}
+		if raw := Base64Decode(string(bytes.TrimSpace(b))); raw != string(bytes.TrimSpace(b)) {
+			candidates = append([][]byte{[]byte(raw)}, candidates...)
+		}
+
+		for _, candidate := range candidates {
+			out, err := decrypt(candidate)
+			if err == nil && utf8.Valid(out) {
+				return out, true, nil
+			}
+		}
+
+		return nil, false, nil
+	}, nil
+}
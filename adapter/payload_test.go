@@ -0,0 +1,197 @@
+package adapter_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"github.com/andybalholm/brotli"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"github.com/klauspost/compress/zstd"
+	"strings"
+	"testing"
+)
+
+const payloadLinks = "trojan://pwd@1.1.1.1:443#trojan\nss://YWVzLTEyOC1nY206cHdk@1.1.1.2:8388#ss\n"
+
+var payloadKey = []byte("0123456789abcdef0123456789abcdef")
+
+func gzipPayload(b []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write(b)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func zstdPayload(b []byte) []byte {
+	w, _ := zstd.NewWriter(nil)
+	defer w.Close()
+	return w.EncodeAll(b, nil)
+}
+
+func brotliPayload(b []byte) []byte {
+	var buf bytes.Buffer
+	w := brotli.NewWriter(&buf)
+	_, _ = w.Write(b)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func gcmPayload(b []byte) []byte {
+	block, _ := aes.NewCipher(payloadKey)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := bytes.Repeat([]byte{1}, gcm.NonceSize())
+	return gcm.Seal(nonce, nonce, b, nil)
+}
+
+func cbcPayload(b []byte, iv []byte) []byte {
+	block, _ := aes.NewCipher(payloadKey)
+	padding := aes.BlockSize - len(b)%aes.BlockSize
+	b = append(append([]byte{}, b...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	out := make([]byte, len(b))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, b)
+	return out
+}
+
+func TestParseSubscriptionPayload(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(payloadLinks))
+	iv := bytes.Repeat([]byte{2}, aes.BlockSize)
+
+	tests := []struct {
+		name string
+		body []byte
+		opt  adapter.ParseOption
+	}{
+		{name: "gzip", body: gzipPayload([]byte(encoded))},
+		{name: "zstd", body: zstdPayload([]byte(payloadLinks))},
+		{name: "brotli", body: brotliPayload([]byte(encoded))},
+		{name: "json", body: []byte(`{"code":0,"data":"` + encoded + `"}`)},
+		{name: "json nested", body: []byte(`{"data":{"content":"` + encoded + `"}}`)},
+		{name: "gzip json", body: gzipPayload([]byte(`{"data":"` + encoded + `"}`))},
+		{
+			name: "gcm",
+			body: []byte(base64.StdEncoding.EncodeToString(gcmPayload([]byte(payloadLinks)))),
+			opt:  adapter.ParseOption{Decryption: &adapter.PayloadCipher{Mode: "gcm", Key: payloadKey}},
+		},
+		{
+			name: "cbc",
+			body: append(append([]byte{}, iv...), cbcPayload([]byte(encoded), iv)...),
+			opt:  adapter.ParseOption{Decryption: &adapter.PayloadCipher{Mode: "cbc", Key: payloadKey}},
+		},
+		{
+			name: "cbc iv json",
+			body: []byte(`{"data":"` + base64.StdEncoding.EncodeToString(cbcPayload([]byte(payloadLinks), iv)) + `"}`),
+			opt:  adapter.ParseOption{Decryption: &adapter.PayloadCipher{Mode: "cbc", Key: payloadKey, IV: iv}},
+		},
+		{
+			name: "custom",
+			body: []byte("reversed:" + reverse(encoded)),
+			opt: adapter.ParseOption{Decoders: append([]adapter.PayloadDecoder{
+				func(b []byte) ([]byte, bool, error) {
+					if !bytes.HasPrefix(b, []byte("reversed:")) {
+						return nil, false, nil
+					}
+					return []byte(reverse(string(b[len("reversed:"):]))), true, nil
+				},
+			}, adapter.DefaultPayloadDecoders...)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := adapter.ParseSubscriptionReport(tt.body, tt.opt)
+			if report.Format != adapter.SubscriptionFormatLink || len(report.Adapters) != 2 {
+				t.Errorf("format:%v nodes:%v rejections:%v", report.Format, report.Adapters, report.Rejections)
+			}
+		})
+	}
+
+	// 密钥错误时无法解析
+	wrongKey := adapter.ParseOption{Decryption: &adapter.PayloadCipher{Key: bytes.Repeat([]byte{3}, 32)}}
+	if nodes := adapter.ParseSubscription([]byte(base64.StdEncoding.EncodeToString(gcmPayload([]byte(payloadLinks)))), wrongKey); len(nodes) != 0 {
+		t.Errorf("nodes = %v", nodes)
+	}
+
+	// sing-box 等 json 配置不是包装
+	b, err := adapter.DecodePayload([]byte(`{"outbounds":[],"data":"x"}`))
+	if err != nil || string(b) != `{"outbounds":[],"data":"x"}` {
+		t.Errorf("b:%s err:%v", b, err)
+	}
+
+	// 损坏的 gzip
+	_, err = adapter.DecodePayload([]byte{0x1f, 0x8b, 0x00})
+	if err == nil {
+		t.Errorf("want error")
+	}
+}
+
+func TestParseSubscriptionStreamPayload(t *testing.T) {
+	for name, body := range map[string][]byte{
+		"gzip": gzipPayload([]byte(payloadLinks)),
+		"zstd": zstdPayload([]byte(base64.StdEncoding.EncodeToString([]byte(payloadLinks)))),
+		"json": []byte(`{"data":"` + base64.StdEncoding.EncodeToString([]byte(payloadLinks)) + `"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			names, _ := collectStream(t, bytes.NewReader(body))
+			if strings.Join(names, ",") != "trojan,ss" {
+				t.Errorf("names = %v", names)
+			}
+		})
+	}
+
+	err := adapter.ParseSubscriptionStream(context.Background(), bytes.NewReader([]byte{0x1f, 0x8b, 0x00}), func(*adapter.Adapter, *adapter.SubscriptionRejection) error {
+		return nil
+	})
+	if err == nil {
+		t.Errorf("want error")
+	}
+}
+
+func TestDecodePayloadMaxSize(t *testing.T) {
+	links := []byte(strings.Repeat(payloadLinks, 1024))
+
+	for name, body := range map[string][]byte{
+		"gzip":   gzipPayload(links),
+		"zstd":   zstdPayload(links),
+		"brotli": brotliPayload(links),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := adapter.DecodePayload(body, adapter.ParseOption{MaxPayloadSize: 1024})
+			if !errors.Is(err, adapter.ErrPayloadTooLarge) {
+				t.Errorf("err = %v, want ErrPayloadTooLarge", err)
+			}
+
+			b, err := adapter.DecodePayload(body, adapter.ParseOption{MaxPayloadSize: int64(len(links))})
+			if err != nil || !bytes.Equal(b, links) {
+				t.Errorf("len:%d err:%v", len(b), err)
+			}
+		})
+	}
+
+	for name, body := range map[string][]byte{
+		"gzip": gzipPayload(links),
+		"zstd": zstdPayload(links),
+	} {
+		t.Run("stream "+name, func(t *testing.T) {
+			err := adapter.ParseSubscriptionStream(context.Background(), bytes.NewReader(body), func(*adapter.Adapter, *adapter.SubscriptionRejection) error {
+				return nil
+			}, adapter.ParseOption{MaxPayloadSize: 1024})
+			if !errors.Is(err, adapter.ErrPayloadTooLarge) {
+				t.Errorf("err = %v, want ErrPayloadTooLarge", err)
+			}
+		})
+	}
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
@@ -0,0 +1,172 @@
+package adapter
+
+import (
+	"github.com/ice-cream-heaven/log"
+	"github.com/metacubex/mihomo/common/structure"
+	"strconv"
+	"strings"
+)
+
+// pluginEscaper SIP003 中参数的 \、;、= 需要使用 \ 转义
+var pluginEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `=`, `\=`)
+
+// splitPlugin 按未转义的 sep 分割，最多分割为 n 段，n < 0 时不限制，保留其中的转义
+func splitPlugin(s string, sep byte, n int) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s) && n != len(parts)+1; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapePlugin 去除 SIP003 参数中的转义
+func unescapePlugin(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parsePlugin 解析 SIP002 中的 plugin 参数，如 obfs-local;obfs=http;obfs-host=example.com
+func parsePlugin(s string) (string, map[string]any) {
+	parts := splitPlugin(s, ';', -1)
+	parts[0] = unescapePlugin(strings.TrimSpace(parts[0]))
+
+	params := map[string]string{}
+	for _, part := range parts[1:] {
+		kv := splitPlugin(part, '=', 2)
+		value := "true"
+		if len(kv) == 2 {
+			value = unescapePlugin(strings.TrimSpace(kv[1]))
+		}
+		params[unescapePlugin(strings.TrimSpace(kv[0]))] = value
+	}
+
+	opts := map[string]any{}
+	set := func(key, value string) {
+		if value != "" {
+			opts[key] = value
+		}
+	}
+
+	switch parts[0] {
+	case "obfs-local", "simple-obfs", "obfs":
+		set("mode", params["obfs"])
+		set("host", params["obfs-host"])
+		return "obfs", opts
+
+	case "v2ray-plugin":
+		opts["mode"] = "websocket"
+		set("mode", params["mode"])
+		set("host", params["host"])
+		set("path", params["path"])
+		if _, ok := params["tls"]; ok {
+			opts["tls"] = true
+		}
+		if params["mux"] == "0" || params["mux"] == "false" {
+			opts["mux"] = false
+		}
+		return "v2ray-plugin", opts
+
+	case "shadow-tls":
+		set("host", params["host"])
+		set("password", params["password"])
+		if version, err := strconv.Atoi(params["version"]); err == nil {
+			opts["version"] = version
+		}
+		return "shadow-tls", opts
+
+	case "restls":
+		set("host", params["host"])
+		set("password", params["password"])
+		set("version-hint", params["version-hint"])
+		set("restls-script", params["restls-script"])
+		return "restls", opts
+
+	default:
+		for key, value := range params {
+			opts[key] = value
+		}
+		return parts[0], opts
+	}
+}
+
+// encodePlugin 生成 SIP002 中的 plugin 参数，与 parsePlugin 相对应
+func encodePlugin(plugin string, pluginOpts map[string]any) string {
+	decoder := structure.NewDecoder(structure.Option{TagName: "obfs", WeaklyTypedInput: true})
+
+	var parts []string
+	switch plugin {
+	case "obfs":
+		o := simpleObfsOption{Host: "bing.com"}
+		err := decoder.Decode(pluginOpts, &o)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return ""
+		}
+
+		parts = append(parts, "obfs-local", "obfs="+pluginEscaper.Replace(o.Mode), "obfs-host="+pluginEscaper.Replace(o.Host))
+
+	case "v2ray-plugin":
+		o := v2rayObfsOption{Host: "bing.com", Mux: true}
+		err := decoder.Decode(pluginOpts, &o)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return ""
+		}
+
+		parts = append(parts, "v2ray-plugin", "mode="+pluginEscaper.Replace(o.Mode), "host="+pluginEscaper.Replace(o.Host))
+		if o.Path != "" {
+			parts = append(parts, "path="+pluginEscaper.Replace(o.Path))
+		}
+		if o.TLS {
+			parts = append(parts, "tls")
+		}
+		if !o.Mux {
+			parts = append(parts, "mux=0")
+		}
+
+	case "shadow-tls":
+		o := shadowTlsOption{Version: 2}
+		err := decoder.Decode(pluginOpts, &o)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return ""
+		}
+
+		parts = append(parts, "shadow-tls", "host="+pluginEscaper.Replace(o.Host), "password="+pluginEscaper.Replace(o.Password), "version="+strconv.Itoa(o.Version))
+
+	case "restls":
+		o := restlsOption{}
+		err := decoder.Decode(pluginOpts, &o)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return ""
+		}
+
+		parts = append(parts, "restls", "host="+pluginEscaper.Replace(o.Host), "password="+pluginEscaper.Replace(o.Password), "version-hint="+pluginEscaper.Replace(o.VersionHint))
+		if o.RestlsScript != "" {
+			parts = append(parts, "restls-script="+pluginEscaper.Replace(o.RestlsScript))
+		}
+
+	default:
+		return ""
+	}
+
+	return strings.Join(parts, ";")
+}
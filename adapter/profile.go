@@ -0,0 +1,257 @@
+package adapter
+
+import (
+	"fmt"
+	"github.com/elliotchance/pie/v2"
+	"github.com/ice-cream-heaven/log"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+const (
+	DefaultClashTestURL  = "https://www.gstatic.com/generate_204"
+	DefaultClashInterval = 300
+)
+
+// DefaultClashRules 默认的规则模板，{proxy}、{auto}、{fallback} 会被替换为对应的策略组名称
+var DefaultClashRules = []string{
+	"GEOIP,LAN,DIRECT,no-resolve",
+	"GEOSITE,CN,DIRECT",
+	"GEOIP,CN,DIRECT",
+	"MATCH,{proxy}",
+}
+
+// ClashProfileOption 生成完整 clash 配置的选项，零值即可使用
+type ClashProfileOption struct {
+	// Base 额外的顶层配置，如 mixed-port、dns 等，proxies、proxy-groups、rules 会被覆盖
+	Base map[string]any
+
+	// Rules 规则模板，为空时使用 DefaultClashRules
+	Rules []string
+
+	ProxyGroupName    string // 默认为 PROXY
+	AutoGroupName     string // 默认为 AUTO
+	FallbackGroupName string // 默认为 FALLBACK
+
+	// RegionGroups 为每个识别出的地区生成 url-test 策略组
+	RegionGroups bool
+
+	TestURL  string // 默认为 DefaultClashTestURL
+	Interval int    // 测速间隔，单位为秒，默认为 DefaultClashInterval
+
+	// UniqueId 保留 proxies 中的 unique_id
+	UniqueId bool
+
+	// ProxyGroups 需要保留的策略组，通常为 SubscriptionReport.ProxyGroups，
+	// 组内的节点根据 Adapter.Groups 重新生成，追加在生成的策略组之后
+	ProxyGroups []*ClashProxyGroup
+}
+
+type clashProfile struct {
+	Proxies     []map[string]any   `yaml:"proxies"`
+	ProxyGroups []*ClashProxyGroup `yaml:"proxy-groups"`
+	Rules       []string           `yaml:"rules"`
+
+	Base map[string]any `yaml:",inline"`
+}
+
+// ToClashProfile 生成完整的 clash 配置，重复的节点会被合并，重名的节点会追加编号
+func ToClashProfile(nodes []*Adapter, opt ClashProfileOption) ([]byte, error) {
+	if opt.ProxyGroupName == "" {
+		opt.ProxyGroupName = "PROXY"
+	}
+	if opt.AutoGroupName == "" {
+		opt.AutoGroupName = "AUTO"
+	}
+	if opt.FallbackGroupName == "" {
+		opt.FallbackGroupName = "FALLBACK"
+	}
+	if opt.TestURL == "" {
+		opt.TestURL = DefaultClashTestURL
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = DefaultClashInterval
+	}
+	if opt.Rules == nil {
+		opt.Rules = DefaultClashRules
+	}
+
+	// 重复的节点合并后保留所有的策略组
+	groups := map[string][]string{}
+	for _, node := range nodes {
+		for _, group := range node.Groups() {
+			if !pie.Contains(groups[node.UniqueId()], group) {
+				groups[node.UniqueId()] = append(groups[node.UniqueId()], group)
+			}
+		}
+	}
+
+	nodes = Merge(&MergeSource{Nodes: nodes}).Adapters()
+
+	profile := &clashProfile{
+		Proxies: []map[string]any{},
+		Base:    map[string]any{},
+	}
+
+	for key, value := range opt.Base {
+		switch key {
+		case "proxies", "proxy-groups", "rules":
+		default:
+			profile.Base[key] = value
+		}
+	}
+
+	// direct、reject 为 clash 内置的策略
+	nodes = pie.Filter(nodes, func(node *Adapter) bool {
+		return node.TypeString() != "direct" && node.TypeString() != "reject"
+	})
+
+	names := pie.Map(nodes, func(node *Adapter) string {
+		return node.Name()
+	})
+
+	exported := map[string]bool{}
+	for _, name := range names {
+		exported[name] = true
+	}
+
+	for _, node := range nodes {
+		// NOTE: 链式节点需要同时导出前面的节点，与其他节点重名时追加编号并更新 dialer-proxy
+		list := node.ToClashChain()
+
+		var prev string
+		for i, m := range list {
+			if !opt.UniqueId {
+				delete(m, "unique_id")
+			}
+
+			if prev != "" {
+				m["dialer-proxy"] = prev
+			}
+
+			if i < len(list)-1 {
+				name := fmt.Sprintf("%v", m["name"])
+				unique := name
+				for n := 2; exported[unique]; n++ {
+					unique = fmt.Sprintf("%s %d", name, n)
+				}
+				exported[unique] = true
+
+				m["name"] = unique
+				prev = unique
+			}
+
+			profile.Proxies = append(profile.Proxies, m)
+		}
+	}
+
+	urlTest := func(name, typ string, proxies []string) *ClashProxyGroup {
+		group := &ClashProxyGroup{
+			Name:     name,
+			Type:     typ,
+			Proxies:  proxies,
+			URL:      opt.TestURL,
+			Interval: opt.Interval,
+		}
+		if typ == "url-test" {
+			group.Tolerance = 50
+		}
+		return group
+	}
+
+	proxy := &ClashProxyGroup{
+		Name: opt.ProxyGroupName,
+		Type: "select",
+	}
+	profile.ProxyGroups = append(profile.ProxyGroups, proxy)
+
+	// NOTE: 规则可能引用 {auto}、{fallback}，没有节点时同样生成，clash 不允许策略组为空，使用 DIRECT 代替
+	members := names
+	if len(members) == 0 {
+		members = []string{"DIRECT"}
+	}
+
+	proxy.Proxies = append(proxy.Proxies, opt.AutoGroupName, opt.FallbackGroupName)
+
+	var regionGroups []*ClashProxyGroup
+	if opt.RegionGroups {
+		members := map[*Region][]string{}
+		for _, name := range names {
+			if region := DetectRegion(name); region != nil {
+				members[region] = append(members[region], name)
+			}
+		}
+
+		for _, region := range Regions {
+			if len(members[region]) == 0 {
+				continue
+			}
+
+			group := urlTest(region.Flag+" "+region.Name, "url-test", members[region])
+			regionGroups = append(regionGroups, group)
+			proxy.Proxies = append(proxy.Proxies, group.Name)
+		}
+	}
+
+	proxy.Proxies = append(proxy.Proxies, names...)
+
+	profile.ProxyGroups = append(profile.ProxyGroups,
+		urlTest(opt.AutoGroupName, "url-test", members),
+		urlTest(opt.FallbackGroupName, "fallback", members),
+	)
+	profile.ProxyGroups = append(profile.ProxyGroups, regionGroups...)
+
+	proxy.Proxies = append(proxy.Proxies, "DIRECT")
+
+	if len(opt.ProxyGroups) > 0 {
+		groupNames := pie.Map(profile.ProxyGroups, func(group *ClashProxyGroup) string {
+			return group.Name
+		})
+		groupNames = append(groupNames, pie.Map(opt.ProxyGroups, func(group *ClashProxyGroup) string {
+			return group.Name
+		})...)
+
+		for _, group := range opt.ProxyGroups {
+			g := *group
+			// provider 已经展开为节点，不再需要 use
+			g.Use = nil
+			g.Proxies = nil
+
+			// 节点可能已经被重命名，只保留引用的策略组以及内置策略
+			for _, name := range group.Proxies {
+				if pie.Contains(groupNames, name) || pie.Contains(clashBuiltinProxies, name) {
+					g.Proxies = append(g.Proxies, name)
+				}
+			}
+
+			for _, node := range nodes {
+				if pie.Contains(groups[node.UniqueId()], group.Name) && pie.Contains(names, node.Name()) {
+					g.Proxies = append(g.Proxies, node.Name())
+				}
+			}
+
+			if len(g.Proxies) == 0 {
+				g.Proxies = append(g.Proxies, "DIRECT")
+			}
+
+			profile.ProxyGroups = append(profile.ProxyGroups, &g)
+		}
+	}
+
+	replacer := strings.NewReplacer(
+		"{proxy}", opt.ProxyGroupName,
+		"{auto}", opt.AutoGroupName,
+		"{fallback}", opt.FallbackGroupName,
+	)
+	for _, rule := range opt.Rules {
+		profile.Rules = append(profile.Rules, replacer.Replace(rule))
+	}
+
+	b, err := yaml.Marshal(profile)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return b, nil
+}
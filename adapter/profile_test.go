@@ -0,0 +1,133 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+)
+
+func TestToClashProfile(t *testing.T) {
+	nodes := adapter.ParseSubscription([]byte(`trojan://pwd@1.1.1.1:443?alpn=h2,http/1.1&sni=example.com#香港 01
+trojan://pwd@1.1.1.1:443#duplicate
+ss://YWVzLTEyOC1nY206cHdk@1.1.1.2:8388#香港 01
+vless://7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d@1.1.1.3:443?security=tls&type=grpc&serviceName=grpc#Japan
+`))
+
+	b, err := adapter.ToClashProfile(nodes, adapter.ClashProfileOption{
+		Base:         map[string]any{"mixed-port": 7890, "rules": []string{"ignored"}},
+		Rules:        []string{"DOMAIN-SUFFIX,google.com,{auto}", "MATCH,{proxy}"},
+		RegionGroups: true,
+	})
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	var profile struct {
+		MixedPort   int              `yaml:"mixed-port"`
+		Proxies     []map[string]any `yaml:"proxies"`
+		ProxyGroups []struct {
+			Name    string   `yaml:"name"`
+			Type    string   `yaml:"type"`
+			Proxies []string `yaml:"proxies"`
+		} `yaml:"proxy-groups"`
+		Rules []string `yaml:"rules"`
+	}
+	err = yaml.Unmarshal(b, &profile)
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	if profile.MixedPort != 7890 {
+		t.Errorf("mixed-port = %d", profile.MixedPort)
+	}
+
+	if !reflect.DeepEqual(profile.Rules, []string{"DOMAIN-SUFFIX,google.com,AUTO", "MATCH,PROXY"}) {
+		t.Errorf("rules = %v", profile.Rules)
+	}
+
+	if len(profile.Proxies) != 3 {
+		t.Errorf("got %d proxies, want 3", len(profile.Proxies))
+		return
+	}
+
+	for _, m := range profile.Proxies {
+		if _, ok := m["unique_id"]; ok {
+			t.Errorf("unique_id leaked: %v", m)
+		}
+	}
+
+	if !reflect.DeepEqual(profile.Proxies[0]["alpn"], []any{"h2", "http/1.1"}) {
+		t.Errorf("alpn = %v", profile.Proxies[0]["alpn"])
+	}
+
+	groups := map[string][]string{}
+	var order []string
+	for _, group := range profile.ProxyGroups {
+		groups[group.Name] = group.Proxies
+		order = append(order, group.Name+":"+group.Type)
+	}
+
+	if !reflect.DeepEqual(order, []string{"PROXY:select", "AUTO:url-test", "FALLBACK:fallback", "🇭🇰 香港:url-test", "🇯🇵 日本:url-test"}) {
+		t.Errorf("groups = %v", order)
+	}
+
+	if !reflect.DeepEqual(groups["PROXY"], []string{"AUTO", "FALLBACK", "🇭🇰 香港", "🇯🇵 日本", "香港 01", "香港 01 2", "Japan", "DIRECT"}) {
+		t.Errorf("PROXY = %v", groups["PROXY"])
+	}
+
+	if !reflect.DeepEqual(groups["🇭🇰 香港"], []string{"香港 01", "香港 01 2"}) {
+		t.Errorf("香港 = %v", groups["🇭🇰 香港"])
+	}
+
+	// 导出的配置可以重新导入，unique_id 保持不变
+	report := adapter.ParseSubscriptionReport(b)
+	if report.Format != adapter.SubscriptionFormatClash || len(report.Adapters) != 3 || len(report.Rejections) != 0 {
+		t.Errorf("report = %+v", report)
+		return
+	}
+
+	for i, node := range report.Adapters {
+		want := []string{nodes[0].UniqueId(), nodes[2].UniqueId(), nodes[3].UniqueId()}[i]
+		if node.UniqueId() != want {
+			t.Errorf("%s unique id mismatch", node.Name())
+		}
+	}
+
+	// 没有节点时规则引用的策略组同样存在
+	b, err = adapter.ToClashProfile(nil, adapter.ClashProfileOption{
+		UniqueId: true,
+		Rules:    []string{"DOMAIN-SUFFIX,google.com,{auto}", "DOMAIN-SUFFIX,github.com,{fallback}", "MATCH,{proxy}"},
+	})
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	var empty struct {
+		ProxyGroups []struct {
+			Name    string   `yaml:"name"`
+			Proxies []string `yaml:"proxies"`
+		} `yaml:"proxy-groups"`
+	}
+	err = yaml.Unmarshal(b, &empty)
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	groups = map[string][]string{}
+	for _, group := range empty.ProxyGroups {
+		groups[group.Name] = group.Proxies
+	}
+
+	if !reflect.DeepEqual(groups, map[string][]string{
+		"PROXY":    {"AUTO", "FALLBACK", "DIRECT"},
+		"AUTO":     {"DIRECT"},
+		"FALLBACK": {"DIRECT"},
+	}) {
+		t.Errorf("empty profile:\n%s", b)
+	}
+}
@@ -0,0 +1,196 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ice-cream-heaven/log"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ProviderFetcher 拉取 http 类型的 proxy-providers 的内容
+type ProviderFetcher func(ctx context.Context, url string) ([]byte, error)
+
+// ClashProxyGroup clash 配置中的 proxy-groups，未列出的字段保存在 Extra 中
+type ClashProxyGroup struct {
+	Name      string   `yaml:"name"`
+	Type      string   `yaml:"type"`
+	Proxies   []string `yaml:"proxies,omitempty"`
+	Use       []string `yaml:"use,omitempty"`
+	URL       string   `yaml:"url,omitempty"`
+	Interval  int      `yaml:"interval,omitempty"`
+	Tolerance int      `yaml:"tolerance,omitempty"`
+
+	Extra map[string]any `yaml:",inline"`
+}
+
+// clash 内置的策略，可以出现在策略组的 proxies 中
+var clashBuiltinProxies = []string{"DIRECT", "REJECT", "REJECT-DROP", "PASS", "COMPATIBLE", "GLOBAL"}
+
+const (
+	DefaultProviderTimeout = time.Second * 30
+	DefaultProviderMaxSize = 10 * 1024 * 1024
+)
+
+var (
+	// ErrProviderDisabled 未设置 BaseDir 或 Fetcher 时不会读取对应类型的 proxy-providers
+	ErrProviderDisabled = errors.New("provider disabled")
+	// ErrProviderPath provider 的路径不在 BaseDir 内
+	ErrProviderPath = errors.New("provider path outside base dir")
+	// ErrProviderTooLarge provider 的内容超过 DefaultProviderMaxSize
+	ErrProviderTooLarge = errors.New("provider too large")
+)
+
+// FetchProvider 直连拉取 http 类型的 proxy-providers，超时时间为 DefaultProviderTimeout，
+// 内容不超过 DefaultProviderMaxSize，可以作为 ParseOption.Fetcher 使用
+func FetchProvider(ctx context.Context, url string) ([]byte, error) {
+	proxy, err := NewDirect()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultProviderTimeout)
+	defer cancel()
+
+	resp, err := proxy.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		SetHeader("User-Agent", DefaultSubscriptionUserAgent).
+		Get(url)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	defer resp.RawBody().Close()
+
+	if resp.StatusCode() != http.StatusOK {
+		log.Errorf("provider status:%s", resp.Status())
+		return nil, fmt.Errorf("provider status:%s", resp.Status())
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.RawBody(), DefaultProviderMaxSize+1))
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	if len(b) > DefaultProviderMaxSize {
+		log.Errorf("provider %s too large", url)
+		return nil, ErrProviderTooLarge
+	}
+
+	return b, nil
+}
+
+// providerPath 返回 BaseDir 内的路径，订阅内容不可信，不允许绝对路径以及跳出 BaseDir
+func (o ParseOption) providerPath(path string) (string, error) {
+	if o.BaseDir == "" {
+		return "", ErrProviderDisabled
+	}
+
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("%w:%s", ErrProviderPath, path)
+	}
+
+	base, err := filepath.EvalSymlinks(o.BaseDir)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+
+	full, err := filepath.EvalSymlinks(filepath.Join(base, path))
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+
+	// NOTE: 符号链接也可能指向 BaseDir 之外
+	rel, err := filepath.Rel(base, full)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w:%s", ErrProviderPath, path)
+	}
+
+	return full, nil
+}
+
+func (o ParseOption) readProvider(path string) ([]byte, error) {
+	full, err := o.providerPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(full)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	if info.Size() > DefaultProviderMaxSize {
+		return nil, ErrProviderTooLarge
+	}
+
+	return os.ReadFile(full)
+}
+
+// loadProvider 返回 proxy-providers 的内容，inline 的 payload 会被转换为 clash 的 proxies
+func (o ParseOption) loadProvider(m map[string]any) ([]byte, error) {
+	path, _ := m["path"].(string)
+
+	switch m["type"] {
+	case "inline":
+		b, err := yaml.Marshal(map[string]any{
+			"proxies": m["payload"],
+		})
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+		return b, nil
+
+	case "file":
+		if path == "" {
+			return nil, errors.New("provider path is empty")
+		}
+
+		b, err := o.readProvider(path)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+		return b, nil
+
+	case "http":
+		url, _ := m["url"].(string)
+		if url == "" {
+			return nil, errors.New("provider url is empty")
+		}
+
+		if o.Fetcher == nil {
+			return nil, ErrProviderDisabled
+		}
+
+		b, err := o.Fetcher(o.context(), url)
+		if err == nil {
+			return b, nil
+		}
+
+		// 与 clash 一致，拉取失败时使用本地缓存的文件
+		if path != "" {
+			if cache, e := o.readProvider(path); e == nil {
+				log.Warnf("provider %s fetch failed, use cache:%s", url, path)
+				return cache, nil
+			}
+		}
+
+		return nil, err
+
+	default:
+		return nil, ErrUnsupportedType
+	}
+}
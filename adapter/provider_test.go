@@ -0,0 +1,279 @@
+package adapter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const providerConfig = `
+proxies:
+  - {name: direct-node, type: trojan, server: 1.1.1.1, port: 443, password: pwd}
+proxy-providers:
+  inline:
+    type: inline
+    payload:
+      - {name: inline-node, type: ss, server: 1.1.1.2, port: 8388, cipher: aes-128-gcm, password: pwd}
+  local:
+    type: file
+    path: ./providers/local.yaml
+  remote:
+    type: http
+    url: %s
+    path: ./providers/remote.yaml
+  missing:
+    type: file
+    path: ./providers/missing.yaml
+proxy-groups:
+  - {name: Manual, type: select, proxies: [Auto, direct-node, DIRECT], use: [inline]}
+  - {name: Auto, type: url-test, url: "https://www.gstatic.com/generate_204", interval: 300, use: [local, remote]}
+`
+
+func TestParseSubscriptionProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("trojan://pwd@1.1.1.4:443#remote-node"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "providers"), 0755)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "providers", "local.yaml"), []byte(`proxies:
+  - {name: local-node, type: trojan, server: 1.1.1.3, port: 443, password: pwd}
+  - {name: broken, type: unknown}
+`), 0644)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	config := []byte(fmt.Sprintf(providerConfig, server.URL+"/sub"))
+
+	report := adapter.ParseSubscriptionReport(config, adapter.ParseOption{
+		BaseDir: dir,
+		Fetcher: adapter.FetchProvider,
+	})
+	if report.Format != adapter.SubscriptionFormatClash {
+		t.Errorf("format = %v", report.Format)
+	}
+
+	groups := map[string][]string{}
+	for _, node := range report.Adapters {
+		groups[node.Name()] = node.Groups()
+	}
+
+	want := map[string][]string{
+		"direct-node": {"Manual"},
+		"inline-node": {"Manual"},
+		"local-node":  {"Auto"},
+		"remote-node": {"Auto"},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %v, want %v", groups, want)
+	}
+
+	providers := map[string]int{}
+	for _, rejection := range report.Rejections {
+		providers[rejection.Provider] = rejection.Index
+	}
+	if !reflect.DeepEqual(providers, map[string]int{"local": 1, "missing": -1}) {
+		t.Errorf("rejections = %v", providers)
+	}
+
+	if len(report.ProxyGroups) != 2 || report.ProxyGroups[1].Interval != 300 {
+		t.Errorf("proxy groups = %v", report.ProxyGroups)
+	}
+
+	// 自定义的 fetcher，拉取失败时使用本地缓存
+	err = os.WriteFile(filepath.Join(dir, "providers", "remote.yaml"), []byte("trojan://pwd@1.1.1.5:443#cached-node"), 0644)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	var fetched []string
+	nodes := adapter.ParseSubscription(config, adapter.ParseOption{
+		BaseDir: dir,
+		Fetcher: func(ctx context.Context, url string) ([]byte, error) {
+			fetched = append(fetched, url)
+			return nil, errors.New("offline")
+		},
+	})
+
+	if !reflect.DeepEqual(fetched, []string{server.URL + "/sub"}) {
+		t.Errorf("fetched = %v", fetched)
+	}
+
+	var cached bool
+	for _, node := range nodes {
+		cached = cached || node.Name() == "cached-node"
+	}
+	if !cached {
+		t.Errorf("cached provider not used: %v", nodes)
+	}
+}
+
+type providerContextKey struct{}
+
+func TestParseSubscriptionProviderContext(t *testing.T) {
+	config := []byte(fmt.Sprintf(providerConfig, "http://127.0.0.1/sub"))
+
+	var values []any
+	opt := adapter.ParseOption{
+		Fetcher: func(ctx context.Context, url string) ([]byte, error) {
+			values = append(values, ctx.Value(providerContextKey{}))
+			return nil, ctx.Err()
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), providerContextKey{}, "report")
+	adapter.ParseSubscriptionReportContext(ctx, config, opt)
+
+	ctx = context.WithValue(context.Background(), providerContextKey{}, "stream")
+	err := adapter.ParseSubscriptionStream(ctx, bytes.NewReader(config), func(node *adapter.Adapter, rejection *adapter.SubscriptionRejection) error {
+		return nil
+	}, opt)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if !reflect.DeepEqual(values, []any{"report", "stream"}) {
+		t.Errorf("fetcher context = %v", values)
+	}
+}
+
+func TestParseSubscriptionProviderPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "base")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	secret := filepath.Join(root, "secret.yaml")
+	err = os.WriteFile(secret, []byte(`proxies:
+  - {name: secret-node, type: trojan, server: 1.1.1.1, port: 443, password: pwd}
+`), 0644)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = os.Symlink(secret, filepath.Join(dir, "link.yaml"))
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	config := []byte(fmt.Sprintf(`
+proxy-providers:
+  absolute: {type: file, path: %s}
+  passwd: {type: file, path: /etc/passwd}
+  parent: {type: file, path: ../secret.yaml}
+  symlink: {type: file, path: ./link.yaml}
+  cache: {type: http, url: http://127.0.0.1:1/sub, path: ../secret.yaml}
+`, secret))
+
+	tests := []struct {
+		name string
+		opt  adapter.ParseOption
+		want error
+	}{
+		{
+			name: "base dir",
+			opt: adapter.ParseOption{
+				BaseDir: dir,
+				Fetcher: func(ctx context.Context, url string) ([]byte, error) {
+					return nil, errors.New("offline")
+				},
+			},
+			want: adapter.ErrProviderPath,
+		},
+		{
+			name: "disabled",
+			want: adapter.ErrProviderDisabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := adapter.ParseSubscriptionReport(config, tt.opt)
+			if len(report.Adapters) != 0 {
+				t.Errorf("adapters = %v", report.Adapters)
+			}
+
+			if len(report.Rejections) != 5 {
+				t.Errorf("rejections = %v", report.Rejections)
+			}
+
+			for _, rejection := range report.Rejections {
+				// http 的缓存拉取失败时返回 fetcher 的错误
+				if rejection.Provider == "cache" && tt.opt.Fetcher != nil {
+					continue
+				}
+
+				if !errors.Is(rejection.Err, tt.want) {
+					t.Errorf("%s err = %v, want %v", rejection.Provider, rejection.Err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestToClashProfileProxyGroups(t *testing.T) {
+	report := adapter.ParseSubscriptionReport([]byte(`
+proxies:
+  - {name: a, type: trojan, server: 1.1.1.1, port: 443, password: pwd}
+  - {name: b, type: trojan, server: 1.1.1.2, port: 443, password: pwd}
+proxy-providers:
+  inline:
+    type: inline
+    payload:
+      - {name: a, type: ss, server: 1.1.1.3, port: 8388, cipher: aes-128-gcm, password: pwd}
+proxy-groups:
+  - {name: Media, type: select, proxies: [Streaming, b, REJECT], use: [inline]}
+  - {name: Streaming, type: fallback, proxies: [a], lazy: true}
+`))
+
+	b, err := adapter.ToClashProfile(report.Adapters, adapter.ClashProfileOption{
+		ProxyGroups: report.ProxyGroups,
+	})
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	var profile struct {
+		ProxyGroups []map[string]any `yaml:"proxy-groups"`
+	}
+	err = yaml.Unmarshal(b, &profile)
+	if err != nil {
+		t.Errorf("err:%v", err)
+		return
+	}
+
+	groups := map[string]map[string]any{}
+	for _, group := range profile.ProxyGroups {
+		groups[group["name"].(string)] = group
+	}
+
+	// provider 中的 a 与 proxies 中的 a 重名，合并时被重命名为 a 2
+	if !reflect.DeepEqual(groups["Media"]["proxies"], []any{"Streaming", "REJECT", "b", "a 2"}) {
+		t.Errorf("Media = %v", groups["Media"])
+	}
+
+	if _, ok := groups["Media"]["use"]; ok {
+		t.Errorf("use should be removed: %v", groups["Media"])
+	}
+
+	if !reflect.DeepEqual(groups["Streaming"]["proxies"], []any{"a"}) || groups["Streaming"]["lazy"] != true {
+		t.Errorf("Streaming = %v", groups["Streaming"])
+	}
+}
@@ -0,0 +1,381 @@
+package adapter
+
+import (
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/anyx"
+	"github.com/metacubex/mihomo/adapter/outbound"
+	"github.com/metacubex/mihomo/common/structure"
+	"github.com/metacubex/mihomo/constant"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseQuanX 解析 Quantumult X 配置中 [server_local] 的一行
+// https://github.com/crossutility/Quantumult-X/blob/master/sample.conf
+func ParseQuanX(s string, opts ...ParseOption) (*Adapter, error) {
+	fields := splitFields(s)
+
+	typ, addr, ok := strings.Cut(fields[0], "=")
+	if !ok {
+		return nil, ErrParseLink
+	}
+
+	host, portStr, err := net.SplitHostPort(strings.TrimSpace(addr))
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, ErrParseLink
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, ErrParseLink
+	}
+
+	_, params := parseFields(fields[1:])
+
+	m := map[string]any{
+		"name":             params["tag"],
+		"server":           host,
+		"port":             port,
+		"udp":              params["udp-relay"] == "true",
+		"skip-cert-verify": params["tls-verification"] == "false",
+		"fingerprint":      params["tls-cert-sha256"],
+	}
+
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	switch typ {
+	case "shadowsocks":
+		m["type"] = "ss"
+		m["cipher"] = params["method"]
+		m["password"] = params["password"]
+
+		switch params["obfs"] {
+		case "":
+		case "http", "tls":
+			m["plugin"] = "obfs"
+			m["plugin-opts"] = map[string]any{
+				"mode": params["obfs"],
+				"host": params["obfs-host"],
+			}
+		case "ws", "wss":
+			m["plugin"] = "v2ray-plugin"
+			m["plugin-opts"] = map[string]any{
+				"mode": "websocket",
+				"host": params["obfs-host"],
+				"path": params["obfs-uri"],
+				"tls":  params["obfs"] == "wss",
+			}
+		default:
+			log.Errorf("unsupported quantumult x obfs:%s", params["obfs"])
+			return nil, ErrUnsupportedType
+		}
+
+	case "vmess":
+		m["type"] = "vmess"
+		m["uuid"] = params["password"]
+		m["cipher"] = quanXToCipher(params["method"])
+		m["servername"] = params["tls-host"]
+
+		// 默认为 aead
+		m["alterId"] = 0
+		if params["aead"] == "false" {
+			m["alterId"] = 1
+		}
+
+		if !quanXToObfs(m, params) {
+			return nil, ErrUnsupportedType
+		}
+
+	case "trojan":
+		m["type"] = "trojan"
+		m["password"] = params["password"]
+		m["sni"] = params["tls-host"]
+
+		if !quanXToObfs(m, params) {
+			return nil, ErrUnsupportedType
+		}
+		delete(m, "tls")
+
+		switch m["network"] {
+		case nil, "ws":
+		default:
+			log.Errorf("unsupported trojan network:%v", m["network"])
+			return nil, ErrUnsupportedType
+		}
+
+	case "http":
+		m["type"] = "http"
+		m["tls"] = params["over-tls"] == "true"
+		m["sni"] = params["tls-host"]
+		m["username"] = params["username"]
+		m["password"] = params["password"]
+
+	case "socks5":
+		m["type"] = "socks5"
+		m["tls"] = params["over-tls"] == "true"
+		m["username"] = params["username"]
+		m["password"] = params["password"]
+
+	default:
+		log.Debugf("unsupported quantumult x type:%s", typ)
+		return nil, ErrUnsupportedType
+	}
+
+	return ParseClash(compactClash(m), opts...)
+}
+
+// quanXToCipher Quantumult X 中 vmess 的加密方式与 clash 的对应关系
+func quanXToCipher(method string) string {
+	switch method {
+	case "", "chacha20-ietf-poly1305":
+		return "chacha20-poly1305"
+	default:
+		return method
+	}
+}
+
+// quanXToObfs 转换 vmess、trojan 的 obfs 参数
+func quanXToObfs(m map[string]any, params map[string]string) bool {
+	m["tls"] = params["over-tls"] == "true"
+
+	switch params["obfs"] {
+	case "":
+	case "over-tls":
+		m["tls"] = true
+	case "ws", "wss":
+		m["tls"] = m["tls"] == true || params["obfs"] == "wss"
+		m["network"] = "ws"
+		m["ws-opts"] = map[string]any{
+			"path":    params["obfs-uri"],
+			"headers": map[string]any{"Host": params["obfs-host"]},
+		}
+	case "http":
+		m["network"] = "http"
+		m["http-opts"] = map[string]any{
+			"path":    []string{params["obfs-uri"]},
+			"headers": map[string]any{"Host": []string{params["obfs-host"]}},
+		}
+	default:
+		log.Errorf("unsupported quantumult x obfs:%s", params["obfs"])
+		return false
+	}
+
+	return true
+}
+
+// ToQuanX 转换为 Quantumult X 配置中 [server_local] 的一行，不支持的协议返回空字符串
+func (p *Adapter) ToQuanX() string {
+	var fields []string
+	set := func(key, value string) {
+		if value != "" {
+			fields = append(fields, key+"="+value)
+		}
+	}
+	setBool := func(key string, value bool) {
+		if value {
+			fields = append(fields, key+"=true")
+		}
+	}
+
+	switch p.Type() {
+	case constant.Shadowsocks:
+		var opt *outbound.ShadowSocksOption
+		switch x := p.clashOpt.(type) {
+		case outbound.ShadowSocksOption:
+			opt = &x
+		case *outbound.ShadowSocksOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		set("shadowsocks", net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)))
+		set("method", opt.Cipher)
+		set("password", opt.Password)
+
+		decoder := structure.NewDecoder(structure.Option{TagName: "obfs", WeaklyTypedInput: true})
+
+		switch opt.Plugin {
+		case "":
+		case "obfs":
+			o := simpleObfsOption{Host: "bing.com"}
+			err := decoder.Decode(opt.PluginOpts, &o)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return ""
+			}
+
+			set("obfs", o.Mode)
+			set("obfs-host", o.Host)
+
+		case "v2ray-plugin":
+			o := v2rayObfsOption{Host: "bing.com", Mux: true}
+			err := decoder.Decode(opt.PluginOpts, &o)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return ""
+			}
+
+			if o.Mode != "websocket" {
+				return ""
+			}
+
+			if o.TLS {
+				set("obfs", "wss")
+			} else {
+				set("obfs", "ws")
+			}
+			set("obfs-host", o.Host)
+			set("obfs-uri", o.Path)
+
+		default:
+			return ""
+		}
+
+		setBool("udp-relay", opt.UDP)
+
+	case constant.Vmess:
+		var opt *outbound.VmessOption
+		switch x := p.clashOpt.(type) {
+		case outbound.VmessOption:
+			opt = &x
+		case *outbound.VmessOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		method := opt.Cipher
+		switch method {
+		case "chacha20-poly1305", "auto":
+			method = "chacha20-ietf-poly1305"
+		case "aes-128-gcm", "none":
+		default:
+			return ""
+		}
+
+		set("vmess", net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)))
+		set("method", method)
+		set("password", opt.UUID)
+
+		switch opt.Network {
+		case "", "tcp":
+			setBool("over-tls", opt.TLS)
+		case "ws":
+			if opt.TLS {
+				set("obfs", "wss")
+			} else {
+				set("obfs", "ws")
+			}
+			set("obfs-host", opt.WSOpts.Headers["Host"])
+			set("obfs-uri", opt.WSOpts.Path)
+		case "http":
+			if opt.TLS {
+				return ""
+			}
+			set("obfs", "http")
+			if len(opt.HTTPOpts.Headers["Host"]) > 0 {
+				set("obfs-host", opt.HTTPOpts.Headers["Host"][0])
+			}
+			if len(opt.HTTPOpts.Path) > 0 {
+				set("obfs-uri", opt.HTTPOpts.Path[0])
+			}
+		default:
+			return ""
+		}
+
+		set("tls-host", opt.ServerName)
+		if opt.TLS && opt.SkipCertVerify {
+			set("tls-verification", "false")
+		}
+		if opt.AlterID != 0 {
+			set("aead", "false")
+		}
+		setBool("udp-relay", opt.UDP)
+
+	case constant.Trojan:
+		var opt *outbound.TrojanOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TrojanOption:
+			opt = &x
+		case *outbound.TrojanOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		if opt.RealityOpts.PublicKey != "" {
+			return ""
+		}
+
+		set("trojan", net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)))
+		set("password", opt.Password)
+
+		switch opt.Network {
+		case "", "tcp":
+			setBool("over-tls", true)
+		case "ws":
+			set("obfs", "wss")
+			set("obfs-host", opt.WSOpts.Headers["Host"])
+			set("obfs-uri", opt.WSOpts.Path)
+		default:
+			return ""
+		}
+
+		set("tls-host", opt.SNI)
+		if opt.SkipCertVerify {
+			set("tls-verification", "false")
+		}
+		setBool("udp-relay", opt.UDP)
+
+	case constant.Http:
+		var opt *outbound.HttpOption
+		switch x := p.clashOpt.(type) {
+		case outbound.HttpOption:
+			opt = &x
+		case *outbound.HttpOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		set("http", net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)))
+		set("username", opt.UserName)
+		set("password", opt.Password)
+		setBool("over-tls", opt.TLS)
+		set("tls-host", opt.SNI)
+		if opt.TLS && opt.SkipCertVerify {
+			set("tls-verification", "false")
+		}
+
+	case constant.Socks5:
+		var opt *outbound.Socks5Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Socks5Option:
+			opt = &x
+		case *outbound.Socks5Option:
+			opt = x
+		default:
+			return ""
+		}
+
+		set("socks5", net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port)))
+		set("username", opt.UserName)
+		set("password", opt.Password)
+		setBool("over-tls", opt.TLS)
+		if opt.TLS && opt.SkipCertVerify {
+			set("tls-verification", "false")
+		}
+		setBool("udp-relay", opt.UDP)
+
+	default:
+		return ""
+	}
+
+	set("tls-cert-sha256", anyx.ToString(p.opt["fingerprint"]))
+	set("tag", p.Name())
+
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,100 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuanX(t *testing.T) {
+	tests := []struct {
+		name string
+		want map[string]any
+	}{
+		{
+			name: "shadowsocks=1.1.1.1:8388, method=aes-128-gcm, password=pwd, obfs=http, obfs-host=bing.com, udp-relay=true, tag=ss",
+			want: map[string]any{"type": "ss", "name": "ss", "cipher": "aes-128-gcm", "password": "pwd", "udp": true, "plugin": "obfs",
+				"plugin-opts": map[string]any{"mode": "http", "host": "bing.com"}},
+		},
+		{
+			name: "shadowsocks=1.1.1.1:443, method=aes-128-gcm, password=pwd, obfs=wss, obfs-host=example.com, obfs-uri=/ws, tag=ss-wss",
+			want: map[string]any{"type": "ss", "plugin": "v2ray-plugin",
+				"plugin-opts": map[string]any{"mode": "websocket", "host": "example.com", "path": "/ws", "tls": true}},
+		},
+		{
+			name: "vmess=1.1.1.1:443, method=chacha20-ietf-poly1305, password=7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d, obfs=wss, obfs-host=example.com, obfs-uri=/ws, tls-verification=false, tag=vmess",
+			want: map[string]any{"type": "vmess", "uuid": "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d", "cipher": "chacha20-poly1305", "alterId": 0, "tls": true, "skip-cert-verify": true,
+				"network": "ws", "ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "example.com"}}},
+		},
+		{
+			name: "trojan=1.1.1.1:443, password=pwd, over-tls=true, tls-host=example.com, udp-relay=true, tag=trojan",
+			want: map[string]any{"type": "trojan", "password": "pwd", "sni": "example.com", "udp": true},
+		},
+		{
+			name: "http=1.1.1.1:443, username=user, password=pwd, over-tls=true, tls-host=example.com, tag=http",
+			want: map[string]any{"type": "http", "tls": true, "username": "user", "password": "pwd", "sni": "example.com"},
+		},
+		{
+			name: "socks5=[2001:db8::1]:1080, username=user, password=pwd, tag=socks5",
+			want: map[string]any{"type": "socks5", "server": "2001:db8::1", "username": "user", "password": "pwd"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adapter.ParseQuanX(tt.name)
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			m := got.ToClash()
+			for key, value := range tt.want {
+				if !reflect.DeepEqual(m[key], value) {
+					t.Errorf("%s = %#v, want %#v", key, m[key], value)
+				}
+			}
+
+			again, err := adapter.ParseQuanX(got.ToQuanX())
+			if err != nil {
+				t.Errorf("%s error = %v", got.ToQuanX(), err)
+				return
+			}
+
+			if again.UniqueId() != got.UniqueId() || again.Name() != got.Name() {
+				t.Errorf("%s unique id mismatch", got.ToQuanX())
+			}
+
+			detected, err := adapter.ParseProxyLine(tt.name)
+			if err != nil || detected.UniqueId() != got.UniqueId() {
+				t.Errorf("detect err:%v", err)
+			}
+		})
+	}
+}
+
+func TestParseSubscriptionProxyLine(t *testing.T) {
+	nodes := adapter.ParseSubscription([]byte(`#!MANAGED-CONFIG https://example.com/surge.conf
+[General]
+loglevel = notify
+
+[Proxy]
+DIRECT = direct
+surge = trojan, 1.1.1.1, 443, password=pwd, ws=true, ws-headers=Host: example.com
+loon = trojan,1.1.1.1,443,"pwd",tls-name=example.com
+
+[Proxy Group]
+Proxy = select, surge, loon
+
+[server_local]
+shadowsocks=1.1.1.1:8388, method=aes-128-gcm, password=pwd, tag=quanx
+`))
+
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.Name())
+	}
+
+	if !reflect.DeepEqual(names, []string{"surge", "loon", "quanx"}) {
+		t.Errorf("got %v", names)
+	}
+}
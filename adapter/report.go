@@ -0,0 +1,98 @@
+package adapter
+
+import (
+	"errors"
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/json"
+)
+
+type SubscriptionFormat string
+
+const (
+	SubscriptionFormatUnknown   SubscriptionFormat = ""
+	SubscriptionFormatSingBox   SubscriptionFormat = "sing-box"
+	SubscriptionFormatXray      SubscriptionFormat = "xray"
+	SubscriptionFormatSIP008    SubscriptionFormat = "sip008"
+	SubscriptionFormatClash     SubscriptionFormat = "clash"
+	SubscriptionFormatLink      SubscriptionFormat = "link"
+	SubscriptionFormatProxyLine SubscriptionFormat = "proxy-line" // Surge、Loon、Quantumult X
+)
+
+// SubscriptionRejection 订阅中未能解析的一个节点
+type SubscriptionRejection struct {
+	// Line 行号，从 1 开始，json、yaml 等结构化的格式为 0
+	Line int
+	// Index 在 proxies、outbounds、servers 中的下标，按行解析的格式为 -1
+	Index int
+	Raw   string
+	// Provider 来自 clash 的 proxy-providers 时为 provider 的名称，provider 整体加载失败时 Index 为 -1
+	Provider string
+
+	// Kind 为 ErrUnsupportedType、ErrParseLink、ErrInvalidField 或 ErrNewProxy，
+	// ErrInvalidField 时可以通过 errors.As 从 Err 中取出 *FieldError
+	Kind error
+	Err  error
+}
+
+type SubscriptionReport struct {
+	Format     SubscriptionFormat
+	Adapters   []*Adapter
+	Rejections []*SubscriptionRejection
+
+	// Protocols 按协议统计解析成功的节点数，key 为 Adapter.TypeString
+	Protocols map[string]int
+
+	// ProxyGroups clash 配置中的 proxy-groups，节点所属的策略组见 Adapter.Groups
+	ProxyGroups []*ClashProxyGroup
+}
+
+func newSubscriptionReport(format SubscriptionFormat) *SubscriptionReport {
+	return &SubscriptionReport{
+		Format:    format,
+		Protocols: map[string]int{},
+	}
+}
+
+func (r *SubscriptionReport) accept(node *Adapter) {
+	r.Adapters = append(r.Adapters, node)
+	r.Protocols[node.TypeString()]++
+}
+
+// reject 记录解析失败的节点，raw 为原始内容，非字符串时会被序列化为 json
+func (r *SubscriptionReport) reject(line, index int, raw any, err error) {
+	r.Rejections = append(r.Rejections, newSubscriptionRejection(line, index, raw, err))
+}
+
+func newSubscriptionRejection(line, index int, raw any, err error) *SubscriptionRejection {
+	rejection := &SubscriptionRejection{
+		Line:  line,
+		Index: index,
+		Err:   err,
+	}
+
+	switch x := raw.(type) {
+	case string:
+		rejection.Raw = x
+	default:
+		b, e := json.Marshal(x)
+		if e != nil {
+			log.Errorf("err:%v", e)
+		}
+		rejection.Raw = string(b)
+	}
+
+	switch {
+	case errors.Is(err, ErrUnsupportedType):
+		rejection.Kind = ErrUnsupportedType
+	case errors.Is(err, ErrParseLink):
+		rejection.Kind = ErrParseLink
+	case errors.Is(err, ErrInvalidField):
+		rejection.Kind = ErrInvalidField
+	default:
+		rejection.Kind = ErrNewProxy
+	}
+
+	log.Debugf("reject line:%d index:%d err:%v", line, index, err)
+
+	return rejection
+}
@@ -0,0 +1,117 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"testing"
+)
+
+func TestParseSubscriptionReport(t *testing.T) {
+	type rejection struct {
+		Line  int
+		Index int
+		Kind  error
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		format     adapter.SubscriptionFormat
+		protocols  map[string]int
+		rejections []rejection
+	}{
+		{
+			name: "clash",
+			body: `proxies:
+  - {name: ss, type: ss, server: 1.1.1.1, port: 8388, cipher: aes-128-gcm, password: pwd}
+  - {name: unknown, type: unknown, server: 1.1.1.1, port: 443}
+  - {name: trojan, type: trojan, server: 1.1.1.1, port: 443, password: pwd}
+  - {name: vmess, type: vmess, server: 1.1.1.1, port: 443}
+`,
+			format:    adapter.SubscriptionFormatClash,
+			protocols: map[string]int{"ss": 1, "trojan": 1},
+			rejections: []rejection{
+				{Line: 0, Index: 1, Kind: adapter.ErrUnsupportedType},
+				{Line: 0, Index: 3, Kind: adapter.ErrInvalidField},
+			},
+		},
+		{
+			name: "link",
+			body: `trojan://pwd@1.1.1.1:443#trojan
+
+unknown://1.1.1.1:443
+vless://7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d@1.1.1.1:443?type=kcp#kcp
+ss://YWVzLTEyOC1nY206cHdk@1.1.1.1:8388#ss
+ss://1.1.1.1#broken
+`,
+			format:    adapter.SubscriptionFormatLink,
+			protocols: map[string]int{"ss": 1, "trojan": 1},
+			rejections: []rejection{
+				{Line: 3, Index: -1, Kind: adapter.ErrUnsupportedType},
+				{Line: 4, Index: -1, Kind: adapter.ErrUnsupportedType},
+				{Line: 6, Index: -1, Kind: adapter.ErrParseLink},
+			},
+		},
+		{
+			name:      "link all broken",
+			body:      "ss://1.1.1.1#broken\n",
+			format:    adapter.SubscriptionFormatLink,
+			protocols: map[string]int{},
+			rejections: []rejection{
+				{Line: 1, Index: -1, Kind: adapter.ErrParseLink},
+			},
+		},
+		{
+			name: "sing-box",
+			body: `{"outbounds":[
+				{"type":"direct","tag":"direct"},
+				{"type":"tor","tag":"tor"},
+				{"type":"socks","tag":"socks","server":"1.1.1.1","server_port":1080},
+				{"type":"selector","tag":"proxy","outbounds":["socks"]}
+			]}`,
+			format:    adapter.SubscriptionFormatSingBox,
+			protocols: map[string]int{"socks5": 1},
+			rejections: []rejection{
+				{Line: 0, Index: 1, Kind: adapter.ErrUnsupportedType},
+			},
+		},
+		{
+			name: "surge",
+			body: `[Proxy]
+DIRECT = direct
+trojan = trojan, 1.1.1.1, 443, password=pwd
+wireguard = wireguard, section-name=wg
+`,
+			format:    adapter.SubscriptionFormatProxyLine,
+			protocols: map[string]int{"trojan": 1},
+			rejections: []rejection{
+				{Line: 4, Index: -1, Kind: adapter.ErrUnsupportedType},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := adapter.ParseSubscriptionReport([]byte(tt.body))
+
+			if report.Format != tt.format {
+				t.Errorf("format = %v, want %v", report.Format, tt.format)
+			}
+
+			if !reflect.DeepEqual(report.Protocols, tt.protocols) {
+				t.Errorf("protocols = %v, want %v", report.Protocols, tt.protocols)
+			}
+
+			var got []rejection
+			for _, r := range report.Rejections {
+				if r.Raw == "" || r.Err == nil {
+					t.Errorf("empty rejection: %+v", r)
+				}
+				got = append(got, rejection{Line: r.Line, Index: r.Index, Kind: r.Kind})
+			}
+
+			if !reflect.DeepEqual(got, tt.rejections) {
+				t.Errorf("rejections = %+v, want %+v", got, tt.rejections)
+			}
+		})
+	}
+}
@@ -0,0 +1,1104 @@
+package adapter
+
+import (
+	"bytes"
+	"github.com/elliotchance/pie/v2"
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/anyx"
+	"github.com/ice-cream-heaven/utils/json"
+	"github.com/metacubex/mihomo/adapter/outbound"
+	"github.com/metacubex/mihomo/common/structure"
+	"github.com/metacubex/mihomo/constant"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// singBoxList sing-box 中既可以是字符串也可以是数组的字段
+type singBoxList []string
+
+func (l *singBoxList) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*l = singBoxList{s}
+		return nil
+	}
+
+	var list []string
+	err := json.Unmarshal(b, &list)
+	if err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func (l singBoxList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+// singBoxUDPOverTCP udp_over_tcp 既可以是 bool 也可以是 {"enabled": true, "version": 2}
+type singBoxUDPOverTCP struct {
+	Enabled bool `json:"enabled"`
+	Version int  `json:"version,omitempty"`
+}
+
+func (u *singBoxUDPOverTCP) UnmarshalJSON(b []byte) error {
+	var enabled bool
+	if json.Unmarshal(b, &enabled) == nil {
+		*u = singBoxUDPOverTCP{Enabled: enabled}
+		return nil
+	}
+
+	type object singBoxUDPOverTCP
+	var o object
+	err := json.Unmarshal(b, &o)
+	if err != nil {
+		return err
+	}
+	*u = singBoxUDPOverTCP(o)
+	return nil
+}
+
+func (u singBoxUDPOverTCP) MarshalJSON() ([]byte, error) {
+	if u.Version == 0 {
+		return json.Marshal(u.Enabled)
+	}
+
+	type object singBoxUDPOverTCP
+	return json.Marshal(object(u))
+}
+
+type singBoxUTLS struct {
+	Enabled     bool   `json:"enabled"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+type singBoxReality struct {
+	Enabled   bool   `json:"enabled"`
+	PublicKey string `json:"public_key,omitempty"`
+	ShortID   string `json:"short_id,omitempty"`
+}
+
+type singBoxTLS struct {
+	Enabled     bool            `json:"enabled"`
+	DisableSNI  bool            `json:"disable_sni,omitempty"`
+	ServerName  string          `json:"server_name,omitempty"`
+	Insecure    bool            `json:"insecure,omitempty"`
+	ALPN        singBoxList     `json:"alpn,omitempty"`
+	Certificate singBoxList     `json:"certificate,omitempty"`
+	UTLS        *singBoxUTLS    `json:"utls,omitempty"`
+	Reality     *singBoxReality `json:"reality,omitempty"`
+}
+
+type singBoxTransport struct {
+	Type                string                 `json:"type"`
+	Host                singBoxList            `json:"host,omitempty"`
+	Path                string                 `json:"path,omitempty"`
+	Method              string                 `json:"method,omitempty"`
+	Headers             map[string]singBoxList `json:"headers,omitempty"`
+	MaxEarlyData        int                    `json:"max_early_data,omitempty"`
+	EarlyDataHeaderName string                 `json:"early_data_header_name,omitempty"`
+	ServiceName         string                 `json:"service_name,omitempty"`
+}
+
+type singBoxObfs struct {
+	Type     string `json:"type,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// singBoxOutbound sing-box 的 outbound，只包含可以转换为 clash 的字段
+// https://sing-box.sagernet.org/configuration/outbound/
+type singBoxOutbound struct {
+	Type        string      `json:"type"`
+	Tag         string      `json:"tag,omitempty"`
+	Server      string      `json:"server,omitempty"`
+	ServerPort  int         `json:"server_port,omitempty"`
+	ServerPorts singBoxList `json:"server_ports,omitempty"`
+	HopInterval string      `json:"hop_interval,omitempty"`
+	Detour      string      `json:"detour,omitempty"`
+	Network     string      `json:"network,omitempty"`
+
+	// socks 为字符串，shadowtls 为数字
+	Version any `json:"version,omitempty"`
+
+	Method     string             `json:"method,omitempty"`
+	Username   string             `json:"username,omitempty"`
+	Password   string             `json:"password,omitempty"`
+	Plugin     string             `json:"plugin,omitempty"`
+	PluginOpts string             `json:"plugin_opts,omitempty"`
+	UDPOverTCP *singBoxUDPOverTCP `json:"udp_over_tcp,omitempty"`
+
+	UUID                string `json:"uuid,omitempty"`
+	Security            string `json:"security,omitempty"`
+	AlterID             int    `json:"alter_id,omitempty"`
+	GlobalPadding       bool   `json:"global_padding,omitempty"`
+	AuthenticatedLength bool   `json:"authenticated_length,omitempty"`
+	Flow                string `json:"flow,omitempty"`
+	PacketEncoding      string `json:"packet_encoding,omitempty"`
+
+	Path    string                 `json:"path,omitempty"`
+	Headers map[string]singBoxList `json:"headers,omitempty"`
+
+	Up                  string `json:"up,omitempty"`
+	UpMbps              int    `json:"up_mbps,omitempty"`
+	Down                string `json:"down,omitempty"`
+	DownMbps            int    `json:"down_mbps,omitempty"`
+	Auth                string `json:"auth,omitempty"`
+	AuthStr             string `json:"auth_str,omitempty"`
+	RecvWindowConn      int    `json:"recv_window_conn,omitempty"`
+	RecvWindow          int    `json:"recv_window,omitempty"`
+	DisableMTUDiscovery bool   `json:"disable_mtu_discovery,omitempty"`
+
+	// hysteria 为字符串，hysteria2 为 singBoxObfs
+	Obfs any `json:"obfs,omitempty"`
+
+	CongestionControl string `json:"congestion_control,omitempty"`
+	UDPRelayMode      string `json:"udp_relay_mode,omitempty"`
+	UDPOverStream     bool   `json:"udp_over_stream,omitempty"`
+	ZeroRTTHandshake  bool   `json:"zero_rtt_handshake,omitempty"`
+	Heartbeat         string `json:"heartbeat,omitempty"`
+
+	LocalAddress  singBoxList `json:"local_address,omitempty"`
+	PrivateKey    string      `json:"private_key,omitempty"`
+	PeerPublicKey string      `json:"peer_public_key,omitempty"`
+	PreSharedKey  string      `json:"pre_shared_key,omitempty"`
+	Reserved      []int       `json:"reserved,omitempty"`
+	MTU           int         `json:"mtu,omitempty"`
+
+	TLS       *singBoxTLS       `json:"tls,omitempty"`
+	Transport *singBoxTransport `json:"transport,omitempty"`
+}
+
+// ParseSingBox 解析 sing-box 的配置文件或 outbounds 数组，无法转换的 outbound 会被忽略
+func ParseSingBox(b []byte, opts ...ParseOption) ([]*Adapter, error) {
+	report, err := parseSingBox(b, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Adapters, nil
+}
+
+func parseSingBox(b []byte, opts ...ParseOption) (*SubscriptionReport, error) {
+	b = bytes.TrimSpace(b)
+
+	var outbounds []singBoxOutbound
+	if bytes.HasPrefix(b, []byte("[")) {
+		err := json.Unmarshal(b, &outbounds)
+		if err != nil {
+			log.Debugf("err:%v", err)
+			return nil, err
+		}
+	} else {
+		var c struct {
+			Outbounds []singBoxOutbound `json:"outbounds"`
+		}
+		err := json.Unmarshal(b, &c)
+		if err != nil {
+			log.Debugf("err:%v", err)
+			return nil, err
+		}
+
+		if c.Outbounds == nil {
+			return nil, ErrUnsupportedType
+		}
+
+		outbounds = c.Outbounds
+	}
+
+	// v2ray / xray 的配置同样有 outbounds，但使用 protocol 而不是 type
+	if len(outbounds) > 0 && !pie.Any(outbounds, func(o singBoxOutbound) bool { return o.Type != "" }) {
+		return nil, ErrUnsupportedType
+	}
+
+	tags := make(map[string]*singBoxOutbound, len(outbounds))
+	for i := range outbounds {
+		if outbounds[i].Tag != "" {
+			tags[outbounds[i].Tag] = &outbounds[i]
+		}
+	}
+
+	report := newSubscriptionReport(SubscriptionFormatSingBox)
+	for i := range outbounds {
+		m, err := outbounds[i].toClash(tags)
+		if err != nil {
+			report.reject(0, i, outbounds[i], err)
+			continue
+		}
+
+		if m == nil {
+			continue
+		}
+
+		node, err := ParseClash(m, opts...)
+		if err != nil {
+			report.reject(0, i, outbounds[i], err)
+			continue
+		}
+
+		report.accept(node)
+	}
+
+	return report, nil
+}
+
+// toClash 转换为 clash 的配置，direct、selector 等非代理的 outbound 返回 nil
+func (o *singBoxOutbound) toClash(tags map[string]*singBoxOutbound) (map[string]any, error) {
+	m := map[string]any{
+		"name":   o.Tag,
+		"server": o.Server,
+		"port":   o.ServerPort,
+		"udp":    o.Network != "tcp",
+	}
+
+	switch o.Type {
+	case "shadowsocks":
+		m["type"] = "ss"
+		m["cipher"] = o.Method
+		m["password"] = o.Password
+		if o.UDPOverTCP != nil && o.UDPOverTCP.Enabled {
+			m["udp-over-tcp"] = true
+			// NOTE: sing-box 默认使用版本 2，mihomo 默认使用版本 1
+			m["udp-over-tcp-version"] = 2
+			if o.UDPOverTCP.Version != 0 {
+				m["udp-over-tcp-version"] = o.UDPOverTCP.Version
+			}
+		}
+
+		if o.Plugin != "" {
+			m["plugin"], m["plugin-opts"] = parsePlugin(o.Plugin + ";" + o.PluginOpts)
+		}
+
+		// shadowtls 通过 detour 串联
+		if detour, ok := tags[o.Detour]; ok && detour.Type == "shadowtls" {
+			m["server"] = detour.Server
+			m["port"] = detour.ServerPort
+			m["plugin"] = "shadow-tls"
+
+			pluginOpts := map[string]any{
+				"password": detour.Password,
+				"version":  anyx.ToInt(detour.Version),
+			}
+			if detour.TLS != nil {
+				pluginOpts["host"] = detour.TLS.ServerName
+			}
+			m["plugin-opts"] = pluginOpts
+		}
+
+	case "vmess":
+		m["type"] = "vmess"
+		m["uuid"] = o.UUID
+		m["alterId"] = o.AlterID
+		m["cipher"] = o.Security
+		if o.Security == "" {
+			m["cipher"] = "auto"
+		}
+		m["global-padding"] = o.GlobalPadding
+		m["authenticated-length"] = o.AuthenticatedLength
+		m["packet-encoding"] = o.PacketEncoding
+		o.tlsToClash(m, "servername")
+		if !o.transportToClash(m) {
+			return nil, ErrUnsupportedType
+		}
+
+	case "vless":
+		m["type"] = "vless"
+		m["uuid"] = o.UUID
+		m["flow"] = o.Flow
+		m["packet-encoding"] = o.PacketEncoding
+		o.tlsToClash(m, "servername")
+		if !o.transportToClash(m) {
+			return nil, ErrUnsupportedType
+		}
+
+	case "trojan":
+		m["type"] = "trojan"
+		m["password"] = o.Password
+		o.tlsToClash(m, "sni")
+		delete(m, "tls")
+		if !o.transportToClash(m) {
+			return nil, ErrUnsupportedType
+		}
+
+		// trojan 不支持 h2 及 http 传输
+		switch m["network"] {
+		case nil, "ws", "grpc":
+		default:
+			log.Errorf("unsupported trojan network:%v", m["network"])
+			return nil, ErrUnsupportedType
+		}
+
+	case "hysteria":
+		m["type"] = "hysteria"
+		m["up"], m["down"] = o.Up, o.Down
+		if o.UpMbps != 0 {
+			m["up"] = strconv.Itoa(o.UpMbps)
+		}
+		if o.DownMbps != 0 {
+			m["down"] = strconv.Itoa(o.DownMbps)
+		}
+		// 与 ParseHysteria 的默认值保持一致
+		if m["up"] == "" {
+			m["up"] = "10"
+		}
+		if m["down"] == "" {
+			m["down"] = "50"
+		}
+		if obfs, ok := o.Obfs.(string); ok {
+			m["obfs"] = obfs
+		}
+		m["auth"] = o.Auth
+		m["auth-str"] = o.AuthStr
+		m["recv-window-conn"] = o.RecvWindowConn
+		m["recv-window"] = o.RecvWindow
+		m["disable-mtu-discovery"] = o.DisableMTUDiscovery
+		if len(o.ServerPorts) > 0 {
+			m["ports"] = strings.ReplaceAll(strings.Join(o.ServerPorts, ","), ":", "-")
+		}
+		o.tlsToClash(m, "sni")
+		delete(m, "tls")
+
+	case "hysteria2":
+		m["type"] = "hysteria2"
+		m["password"] = o.Password
+		if o.UpMbps != 0 {
+			m["up"] = strconv.Itoa(o.UpMbps)
+		}
+		if o.DownMbps != 0 {
+			m["down"] = strconv.Itoa(o.DownMbps)
+		}
+		if obfs, ok := o.Obfs.(map[string]any); ok {
+			m["obfs"] = anyx.ToString(obfs["type"])
+			m["obfs-password"] = anyx.ToString(obfs["password"])
+		}
+		o.tlsToClash(m, "sni")
+		delete(m, "tls")
+
+	case "tuic":
+		m["type"] = "tuic"
+		m["uuid"] = o.UUID
+		m["password"] = o.Password
+		m["congestion-controller"] = o.CongestionControl
+		m["udp-relay-mode"] = o.UDPRelayMode
+		m["udp-over-stream"] = o.UDPOverStream
+		m["reduce-rtt"] = o.ZeroRTTHandshake
+		if heartbeat, err := time.ParseDuration(o.Heartbeat); err == nil {
+			m["heartbeat-interval"] = int(heartbeat.Milliseconds())
+		}
+		o.tlsToClash(m, "sni")
+		delete(m, "tls")
+
+	case "wireguard":
+		m["type"] = "wireguard"
+		m["private-key"] = o.PrivateKey
+		m["public-key"] = o.PeerPublicKey
+		m["pre-shared-key"] = o.PreSharedKey
+		m["mtu"] = o.MTU
+		if len(o.Reserved) > 0 {
+			m["reserved"] = o.Reserved
+		}
+
+		for _, address := range o.LocalAddress {
+			prefix, err := netip.ParsePrefix(address)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+
+			// 与 ParseLinkWireGuard 一致保留前缀长度，否则 unique_id 不同
+			if prefix.Addr().Is4() {
+				m["ip"] = prefix.String()
+			} else {
+				m["ipv6"] = prefix.String()
+			}
+		}
+
+	case "socks":
+		switch anyx.ToString(o.Version) {
+		case "", "5":
+		default:
+			log.Errorf("unsupported socks version:%v", o.Version)
+			return nil, ErrUnsupportedType
+		}
+
+		m["type"] = "socks5"
+		m["username"] = o.Username
+		m["password"] = o.Password
+
+	case "http":
+		m["type"] = "http"
+		m["username"] = o.Username
+		m["password"] = o.Password
+		if len(o.Headers) > 0 {
+			headers := map[string]any{}
+			for key, value := range o.Headers {
+				headers[key] = strings.Join(value, ",")
+			}
+			m["headers"] = headers
+		}
+		o.tlsToClash(m, "sni")
+
+	// shadowtls 已经合并到 shadowsocks 中
+	case "direct", "block", "dns", "selector", "urltest", "shadowtls":
+		return nil, nil
+
+	default:
+		log.Debugf("unsupported sing-box outbound type:%s", o.Type)
+		return nil, ErrUnsupportedType
+	}
+
+	return compactClash(m), nil
+}
+
+func (o *singBoxOutbound) tlsToClash(m map[string]any, sniKey string) {
+	if o.TLS == nil || !o.TLS.Enabled {
+		return
+	}
+
+	m["tls"] = true
+	m[sniKey] = o.TLS.ServerName
+	m["skip-cert-verify"] = o.TLS.Insecure
+
+	if len(o.TLS.ALPN) > 0 {
+		m["alpn"] = []string(o.TLS.ALPN)
+	}
+
+	if o.TLS.DisableSNI {
+		m["disable-sni"] = true
+	}
+
+	if len(o.TLS.Certificate) > 0 {
+		m["ca-str"] = strings.Join(o.TLS.Certificate, "\n")
+	}
+
+	if o.TLS.UTLS != nil && o.TLS.UTLS.Enabled {
+		m["client-fingerprint"] = o.TLS.UTLS.Fingerprint
+	}
+
+	if o.TLS.Reality != nil && o.TLS.Reality.Enabled {
+		m["reality-opts"] = map[string]any{
+			"public-key": o.TLS.Reality.PublicKey,
+			"short-id":   o.TLS.Reality.ShortID,
+		}
+	}
+}
+
+func (o *singBoxOutbound) transportToClash(m map[string]any) bool {
+	t := o.Transport
+	if t == nil {
+		return true
+	}
+
+	headers := map[string]any{}
+	for key, value := range t.Headers {
+		headers[key] = strings.Join(value, ",")
+	}
+
+	switch t.Type {
+	case "ws", "httpupgrade":
+		m["network"] = "ws"
+
+		wsOpts := map[string]any{
+			"path":                   t.Path,
+			"max-early-data":         t.MaxEarlyData,
+			"early-data-header-name": t.EarlyDataHeaderName,
+		}
+
+		if t.Type == "httpupgrade" {
+			wsOpts["v2ray-http-upgrade"] = true
+			if len(t.Host) > 0 {
+				headers["Host"] = t.Host[0]
+			}
+		}
+
+		if len(headers) > 0 {
+			wsOpts["headers"] = headers
+		}
+
+		m["ws-opts"] = wsOpts
+
+	case "grpc":
+		m["network"] = "grpc"
+		m["grpc-opts"] = map[string]any{
+			"grpc-service-name": t.ServiceName,
+		}
+
+	case "http":
+		// 开启 tls 时为 h2，否则为 http/1.1
+		if o.TLS != nil && o.TLS.Enabled {
+			m["network"] = "h2"
+			m["h2-opts"] = map[string]any{
+				"host": []string(t.Host),
+				"path": t.Path,
+			}
+		} else {
+			httpHeaders := map[string]any{}
+			for key, value := range t.Headers {
+				httpHeaders[key] = []string(value)
+			}
+			if len(t.Host) > 0 {
+				httpHeaders["Host"] = []string(t.Host)
+			}
+
+			path := t.Path
+			if path == "" {
+				path = "/"
+			}
+
+			m["network"] = "http"
+			m["http-opts"] = map[string]any{
+				"method":  t.Method,
+				"path":    []string{path},
+				"headers": httpHeaders,
+			}
+		}
+
+	default:
+		log.Errorf("unsupported sing-box transport:%s", t.Type)
+		return false
+	}
+
+	return true
+}
+
+// ToSingBox 转换为 sing-box 的 outbound，shadow-tls 插件会额外生成一个用于 detour 的 shadowtls outbound。
+// sing-box 不支持的协议返回 nil
+func (p *Adapter) ToSingBox() []map[string]any {
+	var outbounds []singBoxOutbound
+
+	switch p.Type() {
+	case constant.Shadowsocks:
+		var opt *outbound.ShadowSocksOption
+		switch x := p.clashOpt.(type) {
+		case outbound.ShadowSocksOption:
+			opt = &x
+		case *outbound.ShadowSocksOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:       "shadowsocks",
+			Tag:        p.Name(),
+			Server:     opt.Server,
+			ServerPort: opt.Port,
+			Method:     opt.Cipher,
+			Password:   opt.Password,
+		}
+
+		if opt.UDPOverTCP {
+			o.UDPOverTCP = &singBoxUDPOverTCP{Enabled: true, Version: opt.UDPOverTCPVersion}
+			if o.UDPOverTCP.Version == 0 {
+				o.UDPOverTCP.Version = 1
+			}
+		}
+
+		switch opt.Plugin {
+		case "":
+		case "shadow-tls":
+			o2 := shadowTlsOption{Version: 2}
+			decoder := structure.NewDecoder(structure.Option{TagName: "obfs", WeaklyTypedInput: true})
+			err := decoder.Decode(opt.PluginOpts, &o2)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil
+			}
+
+			o.Detour = p.Name() + "-shadowtls"
+			outbounds = append(outbounds, o, singBoxOutbound{
+				Type:       "shadowtls",
+				Tag:        o.Detour,
+				Server:     opt.Server,
+				ServerPort: opt.Port,
+				Version:    o2.Version,
+				Password:   o2.Password,
+				TLS: &singBoxTLS{
+					Enabled:    true,
+					ServerName: o2.Host,
+				},
+			})
+		case "obfs", "v2ray-plugin":
+			plugin := encodePlugin(opt.Plugin, opt.PluginOpts)
+			if plugin == "" {
+				return nil
+			}
+			o.Plugin, o.PluginOpts, _ = strings.Cut(plugin, ";")
+		default:
+			log.Errorf("unsupported sing-box plugin:%s", opt.Plugin)
+			return nil
+		}
+
+		if len(outbounds) == 0 {
+			outbounds = append(outbounds, o)
+		}
+
+	case constant.Vmess:
+		var opt *outbound.VmessOption
+		switch x := p.clashOpt.(type) {
+		case outbound.VmessOption:
+			opt = &x
+		case *outbound.VmessOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:                "vmess",
+			Tag:                 p.Name(),
+			Server:              opt.Server,
+			ServerPort:          opt.Port,
+			UUID:                opt.UUID,
+			Security:            opt.Cipher,
+			AlterID:             opt.AlterID,
+			GlobalPadding:       opt.GlobalPadding,
+			AuthenticatedLength: opt.AuthenticatedLength,
+			PacketEncoding:      opt.PacketEncoding,
+		}
+
+		if opt.TLS {
+			o.TLS = newSingBoxTLS(opt.ServerName, opt.SkipCertVerify, opt.ALPN, opt.ClientFingerprint, opt.RealityOpts)
+		}
+
+		if !o.transportFromClash(opt.Network, opt.WSOpts, opt.HTTPOpts, opt.HTTP2Opts, opt.GrpcOpts) {
+			return nil
+		}
+
+		outbounds = append(outbounds, o)
+
+	case constant.Vless:
+		var opt *outbound.VlessOption
+		switch x := p.clashOpt.(type) {
+		case outbound.VlessOption:
+			opt = &x
+		case *outbound.VlessOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:           "vless",
+			Tag:            p.Name(),
+			Server:         opt.Server,
+			ServerPort:     opt.Port,
+			UUID:           opt.UUID,
+			Flow:           opt.Flow,
+			PacketEncoding: opt.PacketEncoding,
+		}
+
+		if opt.TLS {
+			o.TLS = newSingBoxTLS(opt.ServerName, opt.SkipCertVerify, opt.ALPN, opt.ClientFingerprint, opt.RealityOpts)
+		}
+
+		// 兼容旧版的 ws-path 和 ws-headers
+		wsOpts := opt.WSOpts
+		if wsOpts.Path == "" {
+			wsOpts.Path = opt.WSPath
+		}
+		if len(wsOpts.Headers) == 0 {
+			wsOpts.Headers = opt.WSHeaders
+		}
+
+		if !o.transportFromClash(opt.Network, wsOpts, opt.HTTPOpts, opt.HTTP2Opts, opt.GrpcOpts) {
+			return nil
+		}
+
+		outbounds = append(outbounds, o)
+
+	case constant.Trojan:
+		var opt *outbound.TrojanOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TrojanOption:
+			opt = &x
+		case *outbound.TrojanOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:       "trojan",
+			Tag:        p.Name(),
+			Server:     opt.Server,
+			ServerPort: opt.Port,
+			Password:   opt.Password,
+			TLS:        newSingBoxTLS(opt.SNI, opt.SkipCertVerify, opt.ALPN, opt.ClientFingerprint, opt.RealityOpts),
+		}
+
+		if !o.transportFromClash(opt.Network, opt.WSOpts, outbound.HTTPOptions{}, outbound.HTTP2Options{}, opt.GrpcOpts) {
+			return nil
+		}
+
+		outbounds = append(outbounds, o)
+
+	case constant.Hysteria:
+		var opt *outbound.HysteriaOption
+		switch x := p.clashOpt.(type) {
+		case outbound.HysteriaOption:
+			opt = &x
+		case *outbound.HysteriaOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:                "hysteria",
+			Tag:                 p.Name(),
+			Server:              opt.Server,
+			ServerPort:          opt.Port,
+			UpMbps:              opt.UpSpeed,
+			DownMbps:            opt.DownSpeed,
+			Auth:                opt.Auth,
+			AuthStr:             opt.AuthString,
+			RecvWindowConn:      opt.ReceiveWindowConn,
+			RecvWindow:          opt.ReceiveWindow,
+			DisableMTUDiscovery: opt.DisableMTUDiscovery,
+			TLS:                 newSingBoxTLS(opt.SNI, opt.SkipCertVerify, opt.ALPN, "", outbound.RealityOptions{}),
+		}
+
+		if o.UpMbps == 0 {
+			o.UpMbps = int(outbound.StringToBps(opt.Up) / 125000)
+		}
+
+		if o.DownMbps == 0 {
+			o.DownMbps = int(outbound.StringToBps(opt.Down) / 125000)
+		}
+
+		if opt.Obfs != "" {
+			o.Obfs = opt.Obfs
+		}
+
+		if opt.Ports != "" {
+			o.ServerPorts = strings.Split(strings.ReplaceAll(opt.Ports, "-", ":"), ",")
+		}
+
+		if opt.CustomCAString != "" {
+			o.TLS.Certificate = singBoxList{opt.CustomCAString}
+		}
+
+		outbounds = append(outbounds, o)
+
+	case constant.Hysteria2:
+		var opt *outbound.Hysteria2Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Hysteria2Option:
+			opt = &x
+		case *outbound.Hysteria2Option:
+			opt = x
+		default:
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:       "hysteria2",
+			Tag:        p.Name(),
+			Server:     opt.Server,
+			ServerPort: opt.Port,
+			UpMbps:     int(outbound.StringToBps(opt.Up) / 125000),
+			DownMbps:   int(outbound.StringToBps(opt.Down) / 125000),
+			Password:   opt.Password,
+			TLS:        newSingBoxTLS(opt.SNI, opt.SkipCertVerify, opt.ALPN, "", outbound.RealityOptions{}),
+		}
+
+		if opt.Obfs != "" {
+			o.Obfs = singBoxObfs{
+				Type:     opt.Obfs,
+				Password: opt.ObfsPassword,
+			}
+		}
+
+		if opt.CustomCAString != "" {
+			o.TLS.Certificate = singBoxList{opt.CustomCAString}
+		}
+
+		outbounds = append(outbounds, o)
+
+	case constant.Tuic:
+		var opt *outbound.TuicOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TuicOption:
+			opt = &x
+		case *outbound.TuicOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		// sing-box 只支持 tuic v5
+		if opt.Token != "" {
+			log.Errorf("unsupported sing-box tuic v4")
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:              "tuic",
+			Tag:               p.Name(),
+			Server:            opt.Server,
+			ServerPort:        opt.Port,
+			UUID:              opt.UUID,
+			Password:          opt.Password,
+			CongestionControl: opt.CongestionController,
+			UDPRelayMode:      opt.UdpRelayMode,
+			UDPOverStream:     opt.UDPOverStream,
+			ZeroRTTHandshake:  opt.ReduceRtt,
+			TLS:               newSingBoxTLS(opt.SNI, opt.SkipCertVerify, opt.ALPN, "", outbound.RealityOptions{}),
+		}
+
+		if opt.HeartbeatInterval > 0 {
+			o.Heartbeat = (time.Duration(opt.HeartbeatInterval) * time.Millisecond).String()
+		}
+
+		o.TLS.DisableSNI = opt.DisableSni
+
+		if opt.CustomCAString != "" {
+			o.TLS.Certificate = singBoxList{opt.CustomCAString}
+		}
+
+		outbounds = append(outbounds, o)
+
+	case constant.WireGuard:
+		var opt *outbound.WireGuardOption
+		switch x := p.clashOpt.(type) {
+		case outbound.WireGuardOption:
+			opt = &x
+		case *outbound.WireGuardOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		peer := opt.WireGuardPeerOption
+		if peer.Server == "" && len(opt.Peers) > 0 {
+			peer = opt.Peers[0]
+		}
+
+		o := singBoxOutbound{
+			Type:          "wireguard",
+			Tag:           p.Name(),
+			Server:        peer.Server,
+			ServerPort:    peer.Port,
+			PrivateKey:    opt.PrivateKey,
+			PeerPublicKey: peer.PublicKey,
+			PreSharedKey:  peer.PreSharedKey,
+			MTU:           opt.MTU,
+		}
+
+		for _, b := range peer.Reserved {
+			o.Reserved = append(o.Reserved, int(b))
+		}
+
+		if peer.Ip != "" {
+			o.LocalAddress = append(o.LocalAddress, singBoxPrefix(peer.Ip))
+		}
+
+		if peer.Ipv6 != "" {
+			o.LocalAddress = append(o.LocalAddress, singBoxPrefix(peer.Ipv6))
+		}
+
+		outbounds = append(outbounds, o)
+
+	case constant.Socks5:
+		var opt *outbound.Socks5Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Socks5Option:
+			opt = &x
+		case *outbound.Socks5Option:
+			opt = x
+		default:
+			return nil
+		}
+
+		// sing-box 的 socks 不支持 tls
+		if opt.TLS {
+			log.Errorf("unsupported sing-box socks with tls")
+			return nil
+		}
+
+		outbounds = append(outbounds, singBoxOutbound{
+			Type:       "socks",
+			Tag:        p.Name(),
+			Server:     opt.Server,
+			ServerPort: opt.Port,
+			Version:    "5",
+			Username:   opt.UserName,
+			Password:   opt.Password,
+		})
+
+	case constant.Http:
+		var opt *outbound.HttpOption
+		switch x := p.clashOpt.(type) {
+		case outbound.HttpOption:
+			opt = &x
+		case *outbound.HttpOption:
+			opt = x
+		default:
+			return nil
+		}
+
+		o := singBoxOutbound{
+			Type:       "http",
+			Tag:        p.Name(),
+			Server:     opt.Server,
+			ServerPort: opt.Port,
+			Username:   opt.UserName,
+			Password:   opt.Password,
+		}
+
+		if len(opt.Headers) > 0 {
+			o.Headers = map[string]singBoxList{}
+			for key, value := range opt.Headers {
+				o.Headers[key] = singBoxList{value}
+			}
+		}
+
+		if opt.TLS {
+			o.TLS = newSingBoxTLS(opt.SNI, opt.SkipCertVerify, nil, "", outbound.RealityOptions{})
+		}
+
+		outbounds = append(outbounds, o)
+
+	default:
+		return nil
+	}
+
+	var list []map[string]any
+	for _, o := range outbounds {
+		buf, err := json.Marshal(o)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil
+		}
+
+		var m map[string]any
+		err = json.Unmarshal(buf, &m)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil
+		}
+
+		list = append(list, m)
+	}
+
+	return list
+}
+
+func newSingBoxTLS(serverName string, insecure bool, alpn []string, fingerprint string, reality outbound.RealityOptions) *singBoxTLS {
+	t := &singBoxTLS{
+		Enabled:    true,
+		ServerName: serverName,
+		Insecure:   insecure,
+		ALPN:       alpn,
+	}
+
+	if fingerprint != "" {
+		t.UTLS = &singBoxUTLS{
+			Enabled:     true,
+			Fingerprint: fingerprint,
+		}
+	}
+
+	if reality.PublicKey != "" {
+		t.Reality = &singBoxReality{
+			Enabled:   true,
+			PublicKey: reality.PublicKey,
+			ShortID:   reality.ShortID,
+		}
+	}
+
+	return t
+}
+
+func (o *singBoxOutbound) transportFromClash(network string, ws outbound.WSOptions, http outbound.HTTPOptions, h2 outbound.HTTP2Options, grpc outbound.GrpcOptions) bool {
+	switch network {
+	case "", "tcp":
+	case "ws":
+		t := &singBoxTransport{
+			Type:                "ws",
+			Path:                ws.Path,
+			MaxEarlyData:        ws.MaxEarlyData,
+			EarlyDataHeaderName: ws.EarlyDataHeaderName,
+		}
+
+		if len(ws.Headers) > 0 {
+			t.Headers = map[string]singBoxList{}
+			for key, value := range ws.Headers {
+				t.Headers[key] = singBoxList{value}
+			}
+		}
+
+		if ws.V2rayHttpUpgrade {
+			t.Type = "httpupgrade"
+			t.MaxEarlyData, t.EarlyDataHeaderName = 0, ""
+			if host, ok := t.Headers["Host"]; ok {
+				t.Host = host
+				delete(t.Headers, "Host")
+			}
+		}
+
+		o.Transport = t
+
+	case "grpc":
+		o.Transport = &singBoxTransport{
+			Type:        "grpc",
+			ServiceName: grpc.GrpcServiceName,
+		}
+
+	case "h2":
+		o.Transport = &singBoxTransport{
+			Type: "http",
+			Host: h2.Host,
+			Path: h2.Path,
+		}
+
+	case "http":
+		t := &singBoxTransport{
+			Type:   "http",
+			Method: http.Method,
+			Host:   http.Headers["Host"],
+		}
+
+		if len(http.Path) > 0 {
+			t.Path = http.Path[0]
+		}
+
+		for key, value := range http.Headers {
+			if key == "Host" {
+				continue
+			}
+			if t.Headers == nil {
+				t.Headers = map[string]singBoxList{}
+			}
+			t.Headers[key] = value
+		}
+
+		o.Transport = t
+
+	default:
+		log.Errorf("unsupported sing-box network:%s", network)
+		return false
+	}
+
+	return true
+}
+
+// singBoxPrefix sing-box 的 local_address 需要带前缀长度
+func singBoxPrefix(ip string) string {
+	if strings.Contains(ip, "/") {
+		return ip
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()).String()
+}
@@ -0,0 +1,184 @@
+package adapter_test
+
+import (
+	"encoding/json"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"testing"
+)
+
+func TestParseSingBox(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []map[string]any
+	}{
+		{
+			name: "shadowsocks",
+			json: `[{"type":"shadowsocks","tag":"ss","server":"1.1.1.1","server_port":8388,"method":"aes-128-gcm","password":"pwd","plugin":"obfs-local","plugin_opts":"obfs=http;obfs-host=bing.com"}]`,
+			want: []map[string]any{
+				{"type": "ss", "name": "ss", "server": "1.1.1.1", "port": 8388, "cipher": "aes-128-gcm", "password": "pwd", "udp": true,
+					"plugin": "obfs", "plugin-opts": map[string]any{"mode": "http", "host": "bing.com"}},
+			},
+		},
+		{
+			name: "shadowsocks uot",
+			json: `[
+				{"type":"shadowsocks","tag":"ss","server":"1.1.1.1","server_port":8388,"method":"aes-128-gcm","password":"pwd","udp_over_tcp":true},
+				{"type":"shadowsocks","tag":"ss legacy","server":"1.1.1.1","server_port":8388,"method":"aes-128-gcm","password":"pwd","udp_over_tcp":{"enabled":true,"version":1}},
+				{"type":"shadowsocks","tag":"ss disabled","server":"1.1.1.1","server_port":8388,"method":"aes-128-gcm","password":"pwd","udp_over_tcp":{"enabled":false,"version":2}}
+			]`,
+			want: []map[string]any{
+				{"type": "ss", "name": "ss", "server": "1.1.1.1", "port": 8388, "cipher": "aes-128-gcm", "password": "pwd", "udp": true,
+					"udp-over-tcp": true, "udp-over-tcp-version": 2},
+				{"type": "ss", "name": "ss legacy", "server": "1.1.1.1", "port": 8388, "cipher": "aes-128-gcm", "password": "pwd", "udp": true,
+					"udp-over-tcp": true, "udp-over-tcp-version": 1},
+				{"type": "ss", "name": "ss disabled", "server": "1.1.1.1", "port": 8388, "cipher": "aes-128-gcm", "password": "pwd", "udp": true},
+			},
+		},
+		{
+			name: "shadowtls",
+			json: `[
+				{"type":"shadowsocks","tag":"ss","method":"2022-blake3-aes-128-gcm","password":"8JCsPssfgS8tiRwiMlhARg==","detour":"st"},
+				{"type":"shadowtls","tag":"st","server":"1.1.1.1","server_port":443,"version":3,"password":"pwd","tls":{"enabled":true,"server_name":"www.bing.com"}}
+			]`,
+			want: []map[string]any{
+				{"type": "ss", "name": "ss", "server": "1.1.1.1", "port": 443, "cipher": "2022-blake3-aes-128-gcm", "password": "8JCsPssfgS8tiRwiMlhARg==", "udp": true,
+					"plugin": "shadow-tls", "plugin-opts": map[string]any{"host": "www.bing.com", "password": "pwd", "version": 3}},
+			},
+		},
+		{
+			name: "vmess",
+			json: `[{"type":"vmess","tag":"vmess","server":"1.1.1.1","server_port":443,"uuid":"7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d","security":"auto",
+				"tls":{"enabled":true,"server_name":"example.com","alpn":"h2","utls":{"enabled":true,"fingerprint":"chrome"}},
+				"transport":{"type":"ws","path":"/ws","headers":{"Host":"example.com"},"max_early_data":2048,"early_data_header_name":"Sec-WebSocket-Protocol"}}]`,
+			want: []map[string]any{
+				{"type": "vmess", "name": "vmess", "server": "1.1.1.1", "port": 443, "uuid": "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d", "alterId": 0, "cipher": "auto", "udp": true,
+					"tls": true, "servername": "example.com", "alpn": []string{"h2"}, "client-fingerprint": "chrome", "network": "ws",
+					"ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "example.com"}, "max-early-data": 2048, "early-data-header-name": "Sec-WebSocket-Protocol"}},
+			},
+		},
+		{
+			name: "vless reality",
+			json: `[{"type":"vless","tag":"vless","server":"1.1.1.1","server_port":443,"uuid":"7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d","flow":"xtls-rprx-vision",
+				"tls":{"enabled":true,"server_name":"www.apple.com","utls":{"enabled":true,"fingerprint":"chrome"},"reality":{"enabled":true,"public_key":"Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw","short_id":"6ba85179e30d4fc2"}}}]`,
+			want: []map[string]any{
+				{"type": "vless", "name": "vless", "server": "1.1.1.1", "port": 443, "uuid": "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d", "flow": "xtls-rprx-vision", "udp": true,
+					"tls": true, "servername": "www.apple.com", "client-fingerprint": "chrome", "reality-opts": map[string]any{"public-key": "Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw", "short-id": "6ba85179e30d4fc2"}},
+			},
+		},
+		{
+			name: "trojan",
+			json: `[{"type":"trojan","tag":"trojan","server":"1.1.1.1","server_port":443,"password":"pwd","network":"tcp",
+				"tls":{"enabled":true,"server_name":"example.com","insecure":true},"transport":{"type":"grpc","service_name":"grpc"}}]`,
+			want: []map[string]any{
+				{"type": "trojan", "name": "trojan", "server": "1.1.1.1", "port": 443, "password": "pwd",
+					"sni": "example.com", "skip-cert-verify": true, "network": "grpc", "grpc-opts": map[string]any{"grpc-service-name": "grpc"}},
+			},
+		},
+		{
+			name: "hysteria",
+			json: `[{"type":"hysteria","tag":"hy","server":"1.1.1.1","server_port":443,"server_ports":["1000:2000"],"up_mbps":20,"down_mbps":100,"obfs":"obfs","auth_str":"auth",
+				"tls":{"enabled":true,"server_name":"example.com","alpn":["h3"]}}]`,
+			want: []map[string]any{
+				{"type": "hysteria", "name": "hy", "server": "1.1.1.1", "port": 443, "ports": "1000-2000", "up": "20", "down": "100", "obfs": "obfs", "auth-str": "auth", "udp": true,
+					"sni": "example.com", "alpn": []string{"h3"}},
+			},
+		},
+		{
+			name: "hysteria2",
+			json: `[{"type":"hysteria2","tag":"hy2","server":"1.1.1.1","server_port":443,"password":"pwd","obfs":{"type":"salamander","password":"obfs"},
+				"tls":{"enabled":true,"server_name":"example.com"}}]`,
+			want: []map[string]any{
+				{"type": "hysteria2", "name": "hy2", "server": "1.1.1.1", "port": 443, "password": "pwd", "obfs": "salamander", "obfs-password": "obfs", "udp": true,
+					"sni": "example.com"},
+			},
+		},
+		{
+			name: "tuic",
+			json: `[{"type":"tuic","tag":"tuic","server":"1.1.1.1","server_port":443,"uuid":"7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d","password":"pwd",
+				"congestion_control":"bbr","udp_relay_mode":"native","zero_rtt_handshake":true,"heartbeat":"10s","tls":{"enabled":true,"server_name":"example.com","alpn":["h3"]}}]`,
+			want: []map[string]any{
+				{"type": "tuic", "name": "tuic", "server": "1.1.1.1", "port": 443, "uuid": "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d", "password": "pwd", "udp": true,
+					"congestion-controller": "bbr", "udp-relay-mode": "native", "reduce-rtt": true, "heartbeat-interval": 10000, "sni": "example.com", "alpn": []string{"h3"}},
+			},
+		},
+		{
+			name: "socks http",
+			json: `{"outbounds":[
+				{"type":"direct","tag":"direct"},
+				{"type":"socks","tag":"socks","server":"1.1.1.1","server_port":1080,"version":"5","username":"user","password":"pwd"},
+				{"type":"socks","tag":"socks4","server":"1.1.1.1","server_port":1080,"version":"4"},
+				{"type":"http","tag":"http","server":"1.1.1.1","server_port":443,"tls":{"enabled":true,"server_name":"example.com"}},
+				{"type":"selector","tag":"proxy","outbounds":["socks","http"]}
+			]}`,
+			want: []map[string]any{
+				{"type": "socks5", "name": "socks", "server": "1.1.1.1", "port": 1080, "username": "user", "password": "pwd", "udp": true},
+				{"type": "http", "name": "http", "server": "1.1.1.1", "port": 443, "tls": true, "sni": "example.com", "udp": true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := adapter.ParseSingBox([]byte(tt.json))
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			if len(nodes) != len(tt.want) {
+				t.Errorf("got %d nodes, want %d", len(nodes), len(tt.want))
+				return
+			}
+
+			for i, node := range nodes {
+				got := node.ToClash()
+				delete(got, "unique_id")
+
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("got %v\nwant %v", got, tt.want[i])
+				}
+
+				// NOTE: 导出后再导入，unique_id 保持不变
+				buf, err := json.Marshal(map[string]any{"outbounds": node.ToSingBox()})
+				if err != nil {
+					t.Errorf("err:%v", err)
+					return
+				}
+
+				again, err := adapter.ParseSingBox(buf)
+				if err != nil {
+					t.Errorf("err:%v", err)
+					return
+				}
+
+				if len(again) != 1 {
+					t.Errorf("round trip: %s", string(buf))
+					return
+				}
+
+				if again[0].UniqueId() != node.UniqueId() || again[0].Name() != node.Name() {
+					t.Errorf("round trip: %s", string(buf))
+				}
+
+				if uot := again[0].ToClash(); uot["udp-over-tcp"] != got["udp-over-tcp"] || uot["udp-over-tcp-version"] != got["udp-over-tcp-version"] {
+					t.Errorf("round trip: %s", string(buf))
+				}
+			}
+		})
+	}
+}
+
+func TestParseSubscriptionSingBox(t *testing.T) {
+	nodes := adapter.ParseSubscription([]byte(`{
+  "log": {"level": "info"},
+  "outbounds": [
+    {"type": "trojan", "tag": "trojan", "server": "1.1.1.1", "server_port": 443, "password": "pwd", "tls": {"enabled": true}},
+    {"type": "direct", "tag": "direct"}
+  ]
+}`))
+
+	if len(nodes) != 1 || nodes[0].Name() != "trojan" {
+		t.Errorf("got %v", nodes)
+	}
+}
@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/json"
+)
+
+// sip008Server SIP008 中的一个节点
+// https://shadowsocks.org/doc/sip008.html
+type sip008Server struct {
+	ID         string `json:"id,omitempty"`
+	Remarks    string `json:"remarks,omitempty"`
+	Server     string `json:"server"`
+	ServerPort int    `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+	Plugin     string `json:"plugin,omitempty"`
+	PluginOpts string `json:"plugin_opts,omitempty"`
+}
+
+// ParseSIP008 解析 SIP008 格式的 shadowsocks 订阅，无法解析的节点会被忽略
+func ParseSIP008(b []byte, opts ...ParseOption) ([]*Adapter, error) {
+	report, err := parseSIP008(b, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Adapters, nil
+}
+
+func parseSIP008(b []byte, opts ...ParseOption) (*SubscriptionReport, error) {
+	var c struct {
+		Version int             `json:"version"`
+		Servers []*sip008Server `json:"servers"`
+	}
+	err := json.Unmarshal(b, &c)
+	if err != nil {
+		log.Debugf("err:%v", err)
+		return nil, err
+	}
+
+	if c.Servers == nil {
+		return nil, ErrUnsupportedType
+	}
+
+	report := newSubscriptionReport(SubscriptionFormatSIP008)
+	for i, server := range c.Servers {
+		m := map[string]any{
+			"type":     "ss",
+			"name":     server.Remarks,
+			"server":   server.Server,
+			"port":     server.ServerPort,
+			"cipher":   server.Method,
+			"password": server.Password,
+			"udp":      true,
+		}
+
+		switch {
+		case server.Plugin == "":
+		case server.PluginOpts == "":
+			m["plugin"], m["plugin-opts"] = parsePlugin(server.Plugin)
+		default:
+			m["plugin"], m["plugin-opts"] = parsePlugin(server.Plugin + ";" + server.PluginOpts)
+		}
+
+		node, err := ParseClash(m, opts...)
+		if err != nil {
+			report.reject(0, i, server, err)
+			continue
+		}
+
+		report.accept(node)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,41 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"testing"
+)
+
+func TestParseSIP008(t *testing.T) {
+	nodes := adapter.ParseSubscription([]byte(`{
+  "version": 1,
+  "servers": [
+    {"id": "27b8a625-4f4b-4428-9f0f-8a2317db7c79", "remarks": "ss", "server": "1.1.1.1", "server_port": 8388, "password": "pwd", "method": "aes-128-gcm"},
+    {"id": "7842c068-c667-41f2-8f7d-04feece3cb67", "remarks": "obfs", "server": "1.1.1.1", "server_port": 8389, "password": "pwd", "method": "chacha20-ietf-poly1305",
+      "plugin": "obfs-local", "plugin_opts": "obfs=http;obfs-host=bing.com"}
+  ],
+  "bytes_used": 274877906944,
+  "bytes_remaining": 824633720832
+}`))
+
+	links := []string{
+		"ss://YWVzLTEyOC1nY206cHdk@1.1.1.1:8388#ss",
+		"ss://Y2hhY2hhMjAtaWV0Zi1wb2x5MTMwNTpwd2Q@1.1.1.1:8389/?plugin=obfs-local%3Bobfs%3Dhttp%3Bobfs-host%3Dbing.com#obfs",
+	}
+
+	if len(nodes) != len(links) {
+		t.Errorf("got %d nodes, want %d", len(nodes), len(links))
+		return
+	}
+
+	for i, link := range links {
+		want, err := adapter.ParseLink(link)
+		if err != nil {
+			t.Errorf("err:%v", err)
+			return
+		}
+
+		if nodes[i].UniqueId() != want.UniqueId() || nodes[i].Name() != want.Name() {
+			t.Errorf("got %v\nwant %v", nodes[i].ToClash(), want.ToClash())
+		}
+	}
+}
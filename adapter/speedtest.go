@@ -0,0 +1,252 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ice-cream-heaven/log"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultSpeedTestDuration       = time.Second * 10
+	DefaultSpeedTestSampleInterval = time.Second
+	DefaultSpeedTestStallTimeout   = time.Second * 3
+	DefaultSpeedTestUploadSize     = 10 * 1024 * 1024
+)
+
+// SpeedTestOption 测速的选项，DownloadURL 与 UploadURL 至少设置一个
+type SpeedTestOption struct {
+	DownloadURL string
+	// UploadURL 设置后通过 POST 上传 UploadSize 字节
+	UploadURL  string
+	UploadSize int64 // 每个连接上传的字节数，默认为 DefaultSpeedTestUploadSize
+
+	Streams int // 并发的连接数，默认为 1
+
+	// Duration 下载、上传各自的最长时间，超过后提前结束，默认为 DefaultSpeedTestDuration
+	Duration       time.Duration
+	SampleInterval time.Duration // 默认为 DefaultSpeedTestSampleInterval
+	// StallTimeout 超过该时间没有收发任何数据视为卡住并提前结束，默认为 DefaultSpeedTestStallTimeout
+	StallTimeout time.Duration
+}
+
+// SpeedSample 一个采样周期内的速度
+type SpeedSample struct {
+	// Elapsed 采样时距离开始的时间
+	Elapsed        time.Duration
+	BytesPerSecond float64
+}
+
+type SpeedResult struct {
+	Bytes    int64
+	Duration time.Duration
+	// BytesPerSecond 整体的平均速度
+	BytesPerSecond float64
+	// Peak 采样中的最大速度
+	Peak    float64
+	Samples []SpeedSample
+
+	// Stalled 是否因为长时间没有数据而提前结束
+	Stalled bool
+	// Err 连接失败等错误，达到 Duration 或者卡住提前结束时为 nil
+	Err error
+}
+
+type SpeedTestResult struct {
+	Download *SpeedResult
+	Upload   *SpeedResult
+}
+
+// SpeedTest 通过节点下载、上传测试带宽，未设置的 url 对应的结果为 nil
+func (p *Adapter) SpeedTest(ctx context.Context, opt SpeedTestOption) (*SpeedTestResult, error) {
+	if opt.DownloadURL == "" && opt.UploadURL == "" {
+		return nil, errors.New("download url or upload url is required")
+	}
+	if opt.UploadSize <= 0 {
+		opt.UploadSize = DefaultSpeedTestUploadSize
+	}
+	if opt.Streams <= 0 {
+		opt.Streams = 1
+	}
+	if opt.Duration <= 0 {
+		opt.Duration = DefaultSpeedTestDuration
+	}
+	if opt.SampleInterval <= 0 {
+		opt.SampleInterval = DefaultSpeedTestSampleInterval
+	}
+	if opt.StallTimeout <= 0 {
+		opt.StallTimeout = DefaultSpeedTestStallTimeout
+	}
+
+	result := &SpeedTestResult{}
+
+	if opt.DownloadURL != "" {
+		result.Download = p.measureSpeed(ctx, opt, func(ctx context.Context, client *http.Client, counter *atomic.Int64) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, opt.DownloadURL, nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode >= http.StatusBadRequest {
+				return fmt.Errorf("%w:%d", ErrUnexpectedStatus, resp.StatusCode)
+			}
+
+			_, err = io.Copy(io.Discard, &countingReader{r: resp.Body, counter: counter})
+			return err
+		})
+	}
+
+	if opt.UploadURL != "" {
+		result.Upload = p.measureSpeed(ctx, opt, func(ctx context.Context, client *http.Client, counter *atomic.Int64) error {
+			body := &countingReader{r: io.LimitReader(zeroReader{}, opt.UploadSize), counter: counter}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, opt.UploadURL, body)
+			if err != nil {
+				return err
+			}
+			req.ContentLength = opt.UploadSize
+			req.Header.Set("Content-Type", "application/octet-stream")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+
+			if resp.StatusCode >= http.StatusBadRequest {
+				return fmt.Errorf("%w:%d", ErrUnexpectedStatus, resp.StatusCode)
+			}
+			return nil
+		})
+	}
+
+	return result, ctx.Err()
+}
+
+func (p *Adapter) measureSpeed(ctx context.Context, opt SpeedTestOption, stream func(ctx context.Context, client *http.Client, counter *atomic.Int64) error) *SpeedResult {
+	result := &SpeedResult{}
+
+	ctx, cancel := context.WithTimeout(ctx, opt.Duration)
+	defer cancel()
+
+	var (
+		counter atomic.Int64
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		err     error
+	)
+
+	start := time.Now()
+	for i := 0; i < opt.Streams; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// 每个连接使用独立的 client，避免复用同一个连接
+			client := p.GetClientWithTimeout(0)
+			defer client.CloseIdleConnections()
+
+			e := stream(ctx, client, &counter)
+			// 达到时间或者卡住时主动取消，不视为错误
+			if e != nil && ctx.Err() == nil {
+				errOnce.Do(func() {
+					err = e
+					cancel()
+				})
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(opt.SampleInterval)
+	defer ticker.Stop()
+
+	var last int64
+	lastSample, lastProgress := start, start
+
+	sample := func(now time.Time) {
+		bytes := counter.Load()
+		if bytes > last {
+			lastProgress = now
+		}
+
+		if elapsed := now.Sub(lastSample); elapsed > 0 {
+			speed := float64(bytes-last) / elapsed.Seconds()
+			result.Samples = append(result.Samples, SpeedSample{
+				Elapsed:        now.Sub(start),
+				BytesPerSecond: speed,
+			})
+			if speed > result.Peak {
+				result.Peak = speed
+			}
+		}
+
+		last, lastSample = bytes, now
+	}
+
+loop:
+	for {
+		select {
+		case now := <-ticker.C:
+			sample(now)
+
+			if now.Sub(lastProgress) >= opt.StallTimeout {
+				log.Warnf("speed test stalled after %v", now.Sub(start))
+				result.Stalled = true
+				cancel()
+			}
+
+		case <-done:
+			// 最后一个不完整的采样周期
+			if counter.Load() > last {
+				sample(time.Now())
+			}
+			break loop
+		}
+	}
+
+	result.Duration = time.Since(start)
+	result.Bytes = counter.Load()
+	if result.Duration > 0 {
+		result.BytesPerSecond = float64(result.Bytes) / result.Duration.Seconds()
+	}
+	result.Err = err
+
+	return result
+}
+
+type countingReader struct {
+	r       io.Reader
+	counter *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.counter.Add(int64(n))
+	return n, err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
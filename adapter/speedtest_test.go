@@ -0,0 +1,136 @@
+package adapter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSpeedTest(t *testing.T) {
+	chunk := bytes.Repeat([]byte{'x'}, 32*1024)
+
+	var uploaded atomic.Int64
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+			for size > 0 {
+				n, err := w.Write(chunk[:min(size, len(chunk))])
+				if err != nil {
+					return
+				}
+				size -= n
+			}
+
+		case "/endless":
+			for {
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+		case "/stall":
+			_, _ = w.Write(chunk)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second * 5):
+			}
+
+		case "/upload":
+			n, _ := io.Copy(io.Discard, r.Body)
+			uploaded.Add(n)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer target.Close()
+
+	node := newSocks5Proxy(t).Adapter(t)
+	ctx := context.Background()
+
+	t.Run("download and upload", func(t *testing.T) {
+		result, err := node.SpeedTest(ctx, adapter.SpeedTestOption{
+			DownloadURL:    target.URL + "/download?size=1048576",
+			UploadURL:      target.URL + "/upload",
+			UploadSize:     512 * 1024,
+			Streams:        2,
+			SampleInterval: time.Millisecond * 10,
+		})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		download := result.Download
+		if download.Err != nil || download.Bytes != 2*1048576 || download.Stalled {
+			t.Errorf("download = %+v", download)
+		}
+		if download.BytesPerSecond <= 0 || download.Peak <= 0 || len(download.Samples) == 0 {
+			t.Errorf("download = %+v", download)
+		}
+
+		upload := result.Upload
+		if upload.Err != nil || upload.Bytes != 2*512*1024 || uploaded.Load() != 2*512*1024 {
+			t.Errorf("upload = %+v uploaded:%d", upload, uploaded.Load())
+		}
+	})
+
+	t.Run("cutoff", func(t *testing.T) {
+		result, err := node.SpeedTest(ctx, adapter.SpeedTestOption{
+			DownloadURL:    target.URL + "/endless",
+			Duration:       time.Millisecond * 300,
+			SampleInterval: time.Millisecond * 50,
+		})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		download := result.Download
+		if download.Err != nil || download.Stalled || download.Bytes == 0 || download.Duration > time.Second {
+			t.Errorf("download = %+v", download)
+		}
+
+		if result.Upload != nil {
+			t.Errorf("upload should be nil")
+		}
+	})
+
+	t.Run("stall", func(t *testing.T) {
+		result, err := node.SpeedTest(ctx, adapter.SpeedTestOption{
+			DownloadURL:    target.URL + "/stall",
+			SampleInterval: time.Millisecond * 20,
+			StallTimeout:   time.Millisecond * 200,
+		})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		if !result.Download.Stalled || result.Download.Duration > time.Second*2 || result.Download.Err != nil {
+			t.Errorf("download = %+v", result.Download)
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		result, err := node.SpeedTest(ctx, adapter.SpeedTestOption{
+			DownloadURL: target.URL + "/missing",
+		})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		if !errors.Is(result.Download.Err, adapter.ErrUnexpectedStatus) {
+			t.Errorf("err = %v", result.Download.Err)
+		}
+	})
+}
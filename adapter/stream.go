@@ -0,0 +1,293 @@
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"github.com/ice-cream-heaven/log"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"runtime"
+	"strings"
+)
+
+// 单行链接的最大长度
+const maxStreamLineSize = 1024 * 1024
+
+// StreamHandler 每个节点调用一次，node 与 rejection 有且只有一个不为 nil，返回错误时停止解析
+type StreamHandler func(node *Adapter, rejection *SubscriptionRejection) error
+
+type streamResult struct {
+	node      *Adapter
+	rejection *SubscriptionRejection
+}
+
+type streamJob struct {
+	line   int
+	raw    string
+	result chan []*streamResult
+}
+
+// ParseSubscriptionStream 流式解析订阅，按行增量解码 base64 并使用 ParseOption.Workers 个协程创建节点，
+// 回调的顺序与订阅中的顺序一致。只有链接列表（可以使用 gzip、zstd 压缩）支持流式解析，其他格式会读取全部内容后交给 ParseSubscriptionReport
+func ParseSubscriptionStream(ctx context.Context, r io.Reader, fn StreamHandler, opts ...ParseOption) error {
+	opt := newParseOption(opts...)
+	opt.ctx = ctx
+	opts = []ParseOption{opt}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	// gzip、zstd 边读边解压，解压后的内容同样受 ParseOption.MaxPayloadSize 限制，其他的编码在退回 ParseSubscriptionReport 时处理
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+		defer gr.Close()
+		br = bufio.NewReaderSize(newPayloadLimitReader(gr, opt.MaxPayloadSize), 64*1024)
+
+	case bytes.HasPrefix(magic, zstdMagic):
+		maxSize := opt.MaxPayloadSize
+		if maxSize <= 0 {
+			maxSize = DefaultMaxPayloadSize
+		}
+
+		zr, err := zstd.NewReader(br, zstd.WithDecoderMaxMemory(uint64(maxSize)))
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+		defer zr.Close()
+		br = bufio.NewReaderSize(newPayloadLimitReader(zr, maxSize), 64*1024)
+	}
+
+	peek, err := br.Peek(64 * 1024)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	base64Stream := isBase64Stream(peek)
+
+	// NOTE: 只根据开头的内容判断是否为链接列表，其他格式（如单行的 json）可能超过单行的长度限制，直接读取全部内容
+	if !isLinkStream(peek, base64Stream, opts...) {
+		b, err := io.ReadAll(newPayloadLimitReader(br, opt.MaxPayloadSize))
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+
+		report := ParseSubscriptionReportContext(ctx, b, opts...)
+		for _, node := range report.Adapters {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(node, nil); err != nil {
+				return err
+			}
+		}
+		for _, rejection := range report.Rejections {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(nil, rejection); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var reader io.Reader = br
+	if base64Stream {
+		reader = base64.NewDecoder(base64.RawStdEncoding, &base64StreamReader{r: br})
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan *streamJob)
+	// 按顺序等待结果，同时限制正在处理的行数
+	pending := make(chan *streamJob, workers*2)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				var results []*streamResult
+				for _, result := range parseMixedLine(job.raw, opts...) {
+					if result.err != nil {
+						results = append(results, &streamResult{rejection: newSubscriptionRejection(job.line, -1, result.raw, result.err)})
+						continue
+					}
+
+					results = append(results, &streamResult{node: result.node})
+				}
+
+				job.result <- results
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+
+		submit := func(line int, raw string) bool {
+			raw = strings.TrimSpace(raw)
+			if raw == "" || isCommentLine(raw) {
+				return true
+			}
+
+			job := &streamJob{line: line, raw: raw, result: make(chan []*streamResult, 1)}
+
+			select {
+			case pending <- job:
+			case <-ctx.Done():
+				return false
+			}
+
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return false
+			}
+
+			return true
+		}
+
+		var line int
+		for scanner.Scan() {
+			line++
+			if !submit(line, scanner.Text()) {
+				readErr <- nil
+				return
+			}
+		}
+
+		readErr <- scanner.Err()
+	}()
+
+	// NOTE: 提前返回时需要等待读取的协程退出，返回后不能再读取 r 以及关闭的解压器
+	stop := func(err error) error {
+		cancel()
+		<-readErr
+		return err
+	}
+
+	for job := range pending {
+		var results []*streamResult
+		select {
+		case results = <-job.result:
+		case <-ctx.Done():
+			return stop(ctx.Err())
+		}
+
+		for _, result := range results {
+			err := fn(result.node, result.rejection)
+			if err != nil {
+				return stop(err)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := <-readErr; err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
+
+// isLinkStream 根据第一个非空、非注释的行判断是否为链接列表，开头的内容中没有这样的行时同样按链接列表处理
+func isLinkStream(peek []byte, base64Stream bool, opts ...ParseOption) bool {
+	head := peek
+	if base64Stream {
+		// 开头的内容可能在 base64 的中间截断，忽略错误
+		head, _ = io.ReadAll(base64.NewDecoder(base64.RawStdEncoding, &base64StreamReader{r: bytes.NewReader(peek)}))
+	}
+
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isCommentLine(line) {
+			continue
+		}
+
+		for _, result := range parseMixedLine(line, opts...) {
+			if result.err == nil {
+				return true
+			}
+		}
+
+		// 解析失败的链接同样可以流式处理，json 中的 url 除外
+		return strings.Contains(line, "://") && !strings.HasPrefix(line, "{") && !strings.HasPrefix(line, "[")
+	}
+
+	return true
+}
+
+// isBase64Stream 根据开头的内容判断是否为 base64 编码
+func isBase64Stream(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return false
+	}
+
+	for _, c := range b {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '+', c == '/', c == '-', c == '_', c == '=':
+		case c == '\r', c == '\n', c == ' ', c == '\t':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// base64StreamReader 去掉换行、空白以及填充，并将 url 安全的字符转换为标准字符，配合 RawStdEncoding 使用
+type base64StreamReader struct {
+	r io.Reader
+}
+
+func (b *base64StreamReader) Read(p []byte) (int, error) {
+	for {
+		n, err := b.r.Read(p)
+
+		var i int
+		for _, c := range p[:n] {
+			switch c {
+			case '\r', '\n', ' ', '\t', '=':
+				continue
+			case '-':
+				c = '+'
+			case '_':
+				c = '/'
+			}
+			p[i] = c
+			i++
+		}
+
+		// 全部为空白时继续读取，避免返回 0, nil
+		if i > 0 || err != nil {
+			return i, err
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package adapter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const streamBody = `trojan://pwd@1.1.1.1:443#trojan
+
+unknown://1.1.1.1:443
+ss://YWVzLTEyOC1nY206cHdk@1.1.1.2:8388#ss
+ss://1.1.1.1#broken
+vless://7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d@1.1.1.3:443?security=tls&type=grpc&serviceName=grpc#vless
+`
+
+func collectStream(t *testing.T, r io.Reader, opts ...adapter.ParseOption) ([]string, []int) {
+	var names []string
+	var lines []int
+	err := adapter.ParseSubscriptionStream(context.Background(), r, func(node *adapter.Adapter, rejection *adapter.SubscriptionRejection) error {
+		if node != nil {
+			names = append(names, node.Name())
+		} else {
+			lines = append(lines, rejection.Line)
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	return names, lines
+}
+
+func TestParseSubscriptionStream(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(streamBody))
+
+	// 每 76 个字符换行
+	var wrapped strings.Builder
+	for i := 0; i < len(encoded); i += 76 {
+		wrapped.WriteString(encoded[i:min(i+76, len(encoded))])
+		wrapped.WriteString("\r\n")
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "plain", body: streamBody},
+		{name: "base64", body: encoded},
+		{name: "base64 wrapped", body: wrapped.String()},
+		{name: "base64 url raw", body: base64.RawURLEncoding.EncodeToString([]byte(streamBody))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, lines := collectStream(t, strings.NewReader(tt.body), adapter.ParseOption{Workers: 2})
+
+			if strings.Join(names, ",") != "trojan,ss,vless" {
+				t.Errorf("names = %v", names)
+			}
+
+			if fmt.Sprint(lines) != "[3 5]" {
+				t.Errorf("rejections = %v", lines)
+			}
+		})
+	}
+
+	// 非链接列表时退回到 ParseSubscriptionReport
+	names, _ := collectStream(t, strings.NewReader(`proxies:
+  - {name: clash, type: trojan, server: 1.1.1.1, port: 443, password: pwd}
+`))
+	if strings.Join(names, ",") != "clash" {
+		t.Errorf("names = %v", names)
+	}
+
+	// 超过单行长度限制的 json，包含 url 时也不是链接列表
+	singBox := `{"log":{"comment":"https://` + strings.Repeat("x", 2*1024*1024) + `"},"outbounds":[{"type":"trojan","tag":"sing-box","server":"1.1.1.1","server_port":443,"password":"pwd"}]}`
+	names, _ = collectStream(t, strings.NewReader(singBox))
+	if strings.Join(names, ",") != "sing-box" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestParseSubscriptionStreamStop(t *testing.T) {
+	body := generateLinks(1000)
+
+	stop := errors.New("stop")
+	var count int
+	err := adapter.ParseSubscriptionStream(context.Background(), bytes.NewReader(body), func(node *adapter.Adapter, rejection *adapter.SubscriptionRejection) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || count != 10 {
+		t.Errorf("count:%d err:%v", count, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count = 0
+	err = adapter.ParseSubscriptionStream(ctx, bytes.NewReader(body), func(node *adapter.Adapter, rejection *adapter.SubscriptionRejection) error {
+		count++
+		if count == 10 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || count >= 1000 {
+		t.Errorf("count:%d err:%v", count, err)
+	}
+}
+
+// blockingReader 读完 body 后关闭 blocked 并阻塞，直到 release 被关闭
+type blockingReader struct {
+	body    *bytes.Reader
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	if r.body.Len() > 0 {
+		return r.body.Read(p)
+	}
+	close(r.blocked)
+	<-r.release
+	return 0, io.EOF
+}
+
+func TestParseSubscriptionStreamWaitReader(t *testing.T) {
+	r := &blockingReader{
+		body:    bytes.NewReader(generateLinks(1000)),
+		blocked: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	stop := errors.New("stop")
+	done := make(chan error, 1)
+	go func() {
+		// 足够多的协程，保证读取的协程可以读完 body
+		done <- adapter.ParseSubscriptionStream(context.Background(), r, func(node *adapter.Adapter, rejection *adapter.SubscriptionRejection) error {
+			<-r.blocked
+			return stop
+		}, adapter.ParseOption{Workers: 1000})
+	}()
+
+	// 读取的协程仍在读取 r 时不能返回
+	select {
+	case err := <-done:
+		t.Fatalf("returned while reading:%v", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	close(r.release)
+	if err := <-done; !errors.Is(err, stop) {
+		t.Errorf("err:%v", err)
+	}
+}
+
+func generateLinks(n int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "trojan://pwd%d@10.%d.%d.%d:443?sni=example.com#node-%d\n", i, i>>16&0xff, i>>8&0xff, i&0xff, i)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(b.Bytes()))
+}
+
+func BenchmarkParseSubscription(b *testing.B) {
+	body := generateLinks(50000)
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if nodes := adapter.ParseSubscription(body); len(nodes) != 50000 {
+			b.Fatalf("got %d nodes", len(nodes))
+		}
+	}
+}
+
+func BenchmarkParseSubscriptionStream(b *testing.B) {
+	body := generateLinks(50000)
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var count int
+		err := adapter.ParseSubscriptionStream(context.Background(), bytes.NewReader(body), func(node *adapter.Adapter, rejection *adapter.SubscriptionRejection) error {
+			if node != nil {
+				count++
+			}
+			return nil
+		})
+		if err != nil || count != 50000 {
+			b.Fatalf("got %d nodes err:%v", count, err)
+		}
+	}
+}
@@ -1,47 +1,291 @@
 package adapter
 
 import (
+	"context"
+	"github.com/elliotchance/pie/v2"
 	"github.com/ice-cream-heaven/log"
 	"gopkg.in/yaml.v3"
+	"net"
+	"sort"
 	"strings"
 )
 
-func ParseSubscription(b []byte) (nodes []*Adapter) {
-	// NOTE: clash
+func ParseSubscription(b []byte, opts ...ParseOption) []*Adapter {
+	return ParseSubscriptionReport(b, opts...).Adapters
+}
+
+// ParseSubscriptionReport 解析订阅，返回识别到的格式、解析成功的节点以及每个解析失败的节点及原因
+func ParseSubscriptionReport(b []byte, opts ...ParseOption) *SubscriptionReport {
+	return ParseSubscriptionReportContext(context.Background(), b, opts...)
+}
+
+// ParseSubscriptionReportContext 与 ParseSubscriptionReport 相同，ctx 用于拉取 http 类型的 proxy-providers
+func ParseSubscriptionReportContext(ctx context.Context, b []byte, opts ...ParseOption) *SubscriptionReport {
+	opt := newParseOption(opts...)
+	opt.ctx = ctx
+	opts = []ParseOption{opt}
+
+	// NOTE: 压缩、json 包装、加密
+	if decoded, err := DecodePayload(b, opts...); err == nil {
+		b = decoded
+	}
+
+	// NOTE: sing-box，需要在 clash 之前，json 也可以被当作 yaml 解析
+	{
+		report, err := parseSingBox(b, opts...)
+		if err == nil {
+			return report
+		}
+	}
+
+	// NOTE: v2ray / xray
 	{
-		var c struct {
-			Proxies []map[string]any `yaml:"proxies,omitempty"`
+		report, err := parseXray(b, opts...)
+		if err == nil {
+			return report
 		}
-		err := yaml.Unmarshal(b, &c)
+	}
+
+	// NOTE: SIP008
+	{
+		report, err := parseSIP008(b, opts...)
 		if err == nil {
-			for _, m := range c.Proxies {
-				node, err := ParseClash(m)
-				if err != nil {
-					log.Errorf("err:%v", err)
-					continue
+			return report
+		}
+	}
+
+	// NOTE: clash
+	{
+		report, err := parseSubscriptionClash(b, opts...)
+		if err == nil {
+			return report
+		}
+	}
+
+	// NOTE: base64
+	link := parseSubscriptionLink(b, opts...)
+	if len(link.Adapters) > 0 {
+		return link
+	}
+
+	// NOTE: surge / loon / quantumult x
+	proxyLine := parseSubscriptionProxyLine(b, opts...)
+	if len(proxyLine.Adapters) > 0 {
+		return proxyLine
+	}
+
+	// 都没有解析成功时，有形如链接但解析失败的行则认为是链接，否则按 surge 等格式报告
+	for _, rejection := range link.Rejections {
+		if rejection.Kind != ErrUnsupportedType {
+			return link
+		}
+	}
+
+	if len(proxyLine.Rejections) > 0 {
+		return proxyLine
+	}
+
+	return newSubscriptionReport(SubscriptionFormatUnknown)
+}
+
+func parseSubscriptionClash(b []byte, opts ...ParseOption) (*SubscriptionReport, error) {
+	var c struct {
+		Proxies        []map[string]any          `yaml:"proxies,omitempty"`
+		ProxyProviders map[string]map[string]any `yaml:"proxy-providers,omitempty"`
+		ProxyGroups    []*ClashProxyGroup        `yaml:"proxy-groups,omitempty"`
+	}
+	err := yaml.Unmarshal(b, &c)
+	if err != nil {
+		log.Debugf("err:%v", err)
+		return nil, err
+	}
+
+	opt := newParseOption(opts...)
+	if opt.nested {
+		c.ProxyProviders = nil
+		c.ProxyGroups = nil
+	}
+
+	// 其他格式也可能被当作 yaml 解析，没有 proxies 时继续尝试
+	if c.Proxies == nil && c.ProxyProviders == nil {
+		return nil, ErrUnsupportedType
+	}
+
+	report := newSubscriptionReport(SubscriptionFormatClash)
+	for i, m := range c.Proxies {
+		// ParseClash 会修改传入的 map，需要先记录原始内容
+		raw, err := yaml.Marshal(m)
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+
+		node, err := ParseClash(m, opts...)
+		if err != nil {
+			report.reject(0, i, strings.TrimSpace(string(raw)), err)
+			continue
+		}
+
+		report.accept(node)
+	}
+
+	// NOTE: proxy-providers，按名称排序保证结果稳定
+	providers := map[string][]*Adapter{}
+	names := make([]string, 0, len(c.ProxyProviders))
+	for name := range c.ProxyProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nestedOpt := opt
+	nestedOpt.nested = true
+	for _, name := range names {
+		body, err := opt.loadProvider(c.ProxyProviders[name])
+		if err != nil {
+			report.reject(0, -1, c.ProxyProviders[name], err)
+			report.Rejections[len(report.Rejections)-1].Provider = name
+			continue
+		}
+
+		sub := ParseSubscriptionReport(body, nestedOpt)
+		for _, rejection := range sub.Rejections {
+			rejection.Provider = name
+			report.Rejections = append(report.Rejections, rejection)
+		}
+
+		for _, node := range sub.Adapters {
+			report.accept(node)
+		}
+		providers[name] = sub.Adapters
+	}
+
+	// NOTE: proxy-groups，记录每个节点所属的策略组
+	if len(c.ProxyGroups) > 0 {
+		nodes := map[string]*Adapter{}
+		for _, node := range report.Adapters {
+			if _, ok := nodes[node.Name()]; !ok {
+				nodes[node.Name()] = node
+			}
+		}
+
+		for _, group := range c.ProxyGroups {
+			members := pie.Map(group.Proxies, func(name string) *Adapter {
+				return nodes[name]
+			})
+			for _, use := range group.Use {
+				members = append(members, providers[use]...)
+			}
+
+			for _, node := range members {
+				if node != nil && !pie.Contains(node.groups, group.Name) {
+					node.groups = append(node.groups, group.Name)
 				}
-				nodes = append(nodes, node)
 			}
-			return
 		}
+
+		report.ProxyGroups = c.ProxyGroups
 	}
 
-	// NOTE: base64
-	{
-		for _, link := range strings.Split(Base64Decode(string(b)), "\n") {
-			node, err := ParseLink(link)
-			if err != nil {
-				log.Errorf("err:%v", err)
+	return report, nil
+}
+
+// parseSubscriptionLink 整体不是 base64 时逐行识别，允许混合链接、base64 编码的链接、clash 节点以及注释
+func parseSubscriptionLink(b []byte, opts ...ParseOption) *SubscriptionReport {
+	report := newSubscriptionReport(SubscriptionFormatLink)
+	for i, line := range strings.Split(Base64Decode(string(b)), "\n") {
+		for _, result := range parseMixedLine(line, opts...) {
+			if result.err != nil {
+				report.reject(i+1, -1, result.raw, result.err)
 				continue
 			}
 
-			nodes = append(nodes, node)
+			report.accept(result.node)
 		}
+	}
+
+	return report
+}
+
+func parseSubscriptionProxyLine(b []byte, opts ...ParseOption) *SubscriptionReport {
+	report := newSubscriptionReport(SubscriptionFormatProxyLine)
+
+	var section string
+	for i, line := range strings.Split(Base64Decode(string(b)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+
+		// 只解析节点所在的段落，没有段落时视为节点列表
+		switch section {
+		case "", "proxy", "server_local":
+		default:
+			continue
+		}
+
+		// Surge 中内置的策略
+		if _, value, ok := strings.Cut(line, "="); ok {
+			switch strings.ToLower(strings.TrimSpace(value)) {
+			case "direct", "reject", "reject-tinygif", "reject-drop", "reject-no-drop":
+				continue
+			}
+		}
+
+		node, err := ParseProxyLine(line, opts...)
+		if err != nil {
+			report.reject(i+1, -1, line, err)
+			continue
+		}
+
+		report.accept(node)
+	}
+
+	return report
+}
+
+// ParseProxyLine 解析 Surge、Loon、Quantumult X 配置中的一行节点，自动识别格式
+func ParseProxyLine(line string, opts ...ParseOption) (*Adapter, error) {
+	_, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	fields := splitFields(value)
+
+	// Quantumult X: shadowsocks=host:port, method=..., tag=...
+	if _, _, err := net.SplitHostPort(fields[0]); err == nil {
+		return ParseQuanX(line, opts...)
+	}
+
+	if len(fields) < 3 {
+		return nil, ErrUnsupportedType
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "shadowsocks", "shadowsocksr":
+		return ParseLoon(line, opts...)
+	case "ss", "socks5-tls", "tuic", "tuic-v5":
+		return ParseSurge(line, opts...)
+	}
+
+	// Loon 的密码等为位置参数，并且使用 over-tls、tls-name 等 Surge 中没有的参数
+	args, params := parseFields(fields[3:])
+	switch strings.ToLower(fields[0]) {
+	case "vmess", "trojan", "hysteria2":
+		if len(args) > 0 {
+			return ParseLoon(line, opts...)
+		}
+	}
 
-		if len(nodes) > 0 {
-			return
+	for _, key := range []string{"over-tls", "tls-name", "obfs-name", "transport"} {
+		if _, ok := params[key]; ok {
+			return ParseLoon(line, opts...)
 		}
 	}
 
-	return
+	return ParseSurge(line, opts...)
 }
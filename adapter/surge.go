@@ -0,0 +1,484 @@
+package adapter
+
+import (
+	"github.com/elliotchance/pie/v2"
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/anyx"
+	"github.com/metacubex/mihomo/adapter/outbound"
+	"github.com/metacubex/mihomo/common/structure"
+	"github.com/metacubex/mihomo/constant"
+	"golang.org/x/exp/maps"
+	"strconv"
+	"strings"
+)
+
+// splitFields 按逗号分割，忽略引号中的逗号
+func splitFields(s string) []string {
+	var fields []string
+	var quoted bool
+	var start int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				fields = append(fields, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(fields, strings.TrimSpace(s[start:]))
+}
+
+// parseFields 拆分出 key=value 形式的参数，其余的按顺序作为位置参数，带引号的始终为位置参数
+func parseFields(fields []string) (args []string, params map[string]string) {
+	params = map[string]string{}
+	for _, field := range fields {
+		if strings.HasPrefix(field, `"`) {
+			args = append(args, strings.Trim(field, `"`))
+			continue
+		}
+
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			args = append(args, field)
+			continue
+		}
+
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return args, params
+}
+
+// parseProxyName 拆分 Surge、Loon 中的 Name = type, server, port, ...
+func parseProxyName(s string) (string, string, string, int, []string, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", "", 0, nil, ErrParseLink
+	}
+
+	fields := splitFields(value)
+	if len(fields) < 3 {
+		return "", "", "", 0, nil, ErrParseLink
+	}
+
+	port, err := strconv.Atoi(fields[2])
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", "", "", 0, nil, ErrParseLink
+	}
+
+	return strings.TrimSpace(name), strings.ToLower(fields[0]), strings.Trim(fields[1], "[]"), port, fields[3:], nil
+}
+
+func parseSurgeHeaders(s string) map[string]any {
+	headers := map[string]any{}
+	for _, header := range strings.Split(s, "|") {
+		key, value, ok := strings.Cut(header, ":")
+		if !ok {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// ParseSurge 解析 Surge 配置中 [Proxy] 的一行
+// https://manual.nssurge.com/policy/proxy.html
+func ParseSurge(s string, opts ...ParseOption) (*Adapter, error) {
+	name, typ, server, port, fields, err := parseProxyName(s)
+	if err != nil {
+		return nil, err
+	}
+
+	args, params := parseFields(fields)
+
+	m := map[string]any{
+		"name":             name,
+		"server":           server,
+		"port":             port,
+		"skip-cert-verify": params["skip-cert-verify"] == "true",
+		"fingerprint":      params["server-cert-fingerprint-sha256"],
+	}
+
+	switch typ {
+	case "ss":
+		m["type"] = "ss"
+		m["cipher"] = params["encrypt-method"]
+		m["password"] = params["password"]
+		m["udp"] = params["udp-relay"] == "true"
+
+		if params["obfs"] != "" {
+			m["plugin"] = "obfs"
+			m["plugin-opts"] = map[string]any{
+				"mode": params["obfs"],
+				"host": params["obfs-host"],
+			}
+		}
+
+		if params["shadow-tls-password"] != "" {
+			version, _ := strconv.Atoi(params["shadow-tls-version"])
+			if version == 0 {
+				version = 2
+			}
+
+			m["plugin"] = "shadow-tls"
+			m["plugin-opts"] = map[string]any{
+				"host":     params["shadow-tls-sni"],
+				"password": params["shadow-tls-password"],
+				"version":  version,
+			}
+		}
+
+	case "vmess":
+		m["type"] = "vmess"
+		m["uuid"] = params["username"]
+		m["cipher"] = "auto"
+		m["udp"] = true
+		m["tls"] = params["tls"] == "true"
+		m["servername"] = params["sni"]
+
+		// 非 aead 时需要 alterId 大于 0
+		m["alterId"] = 0
+		if params["vmess-aead"] != "true" {
+			m["alterId"] = 1
+		}
+
+		if params["ws"] == "true" {
+			m["network"] = "ws"
+			m["ws-opts"] = map[string]any{
+				"path":    params["ws-path"],
+				"headers": parseSurgeHeaders(params["ws-headers"]),
+			}
+		}
+
+	case "trojan":
+		m["type"] = "trojan"
+		m["password"] = params["password"]
+		m["sni"] = params["sni"]
+		m["udp"] = params["udp-relay"] == "true"
+
+		if params["ws"] == "true" {
+			m["network"] = "ws"
+			m["ws-opts"] = map[string]any{
+				"path":    params["ws-path"],
+				"headers": parseSurgeHeaders(params["ws-headers"]),
+			}
+		}
+
+	case "http", "https":
+		m["type"] = "http"
+		m["tls"] = typ == "https"
+		m["sni"] = params["sni"]
+		m["username"], m["password"] = params["username"], params["password"]
+		if len(args) >= 2 {
+			m["username"], m["password"] = args[0], args[1]
+		}
+
+	case "socks5", "socks5-tls":
+		m["type"] = "socks5"
+		m["tls"] = typ == "socks5-tls"
+		m["udp"] = params["udp-relay"] == "true"
+		m["username"], m["password"] = params["username"], params["password"]
+		if len(args) >= 2 {
+			m["username"], m["password"] = args[0], args[1]
+		}
+
+	case "snell":
+		version, _ := strconv.Atoi(params["version"])
+
+		m["type"] = "snell"
+		m["psk"] = params["psk"]
+		m["version"] = version
+		// snell v1、v2 不支持 udp
+		m["udp"] = version >= 3
+
+		if params["obfs"] != "" {
+			m["obfs-opts"] = map[string]any{
+				"mode": params["obfs"],
+				"host": params["obfs-host"],
+			}
+		}
+
+	case "tuic", "tuic-v5":
+		m["type"] = "tuic"
+		m["sni"] = params["sni"]
+		m["udp"] = true
+
+		if typ == "tuic-v5" {
+			m["uuid"] = params["uuid"]
+			m["password"] = params["password"]
+		} else {
+			m["token"] = params["token"]
+		}
+
+		if params["alpn"] != "" {
+			m["alpn"] = splitComma(params["alpn"])
+		}
+
+	case "hysteria2":
+		m["type"] = "hysteria2"
+		m["password"] = params["password"]
+		m["sni"] = params["sni"]
+		m["down"] = params["download-bandwidth"]
+		m["udp"] = true
+
+	default:
+		log.Debugf("unsupported surge type:%s", typ)
+		return nil, ErrUnsupportedType
+	}
+
+	return ParseClash(compactClash(m), opts...)
+}
+
+// ToSurge 转换为 Surge 配置中 [Proxy] 的一行，不支持的协议返回空字符串
+func (p *Adapter) ToSurge() string {
+	var fields []string
+	set := func(key, value string) {
+		if value != "" {
+			fields = append(fields, key+"="+value)
+		}
+	}
+	setBool := func(key string, value bool) {
+		if value {
+			fields = append(fields, key+"=true")
+		}
+	}
+
+	switch p.Type() {
+	case constant.Shadowsocks:
+		var opt *outbound.ShadowSocksOption
+		switch x := p.clashOpt.(type) {
+		case outbound.ShadowSocksOption:
+			opt = &x
+		case *outbound.ShadowSocksOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "ss", opt.Server, strconv.Itoa(opt.Port))
+		set("encrypt-method", opt.Cipher)
+		set("password", opt.Password)
+
+		decoder := structure.NewDecoder(structure.Option{TagName: "obfs", WeaklyTypedInput: true})
+
+		switch opt.Plugin {
+		case "":
+		case "obfs":
+			o := simpleObfsOption{Host: "bing.com"}
+			err := decoder.Decode(opt.PluginOpts, &o)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return ""
+			}
+
+			set("obfs", o.Mode)
+			set("obfs-host", o.Host)
+
+		case "shadow-tls":
+			o := shadowTlsOption{Version: 2}
+			err := decoder.Decode(opt.PluginOpts, &o)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return ""
+			}
+
+			set("shadow-tls-password", o.Password)
+			set("shadow-tls-sni", o.Host)
+			set("shadow-tls-version", strconv.Itoa(o.Version))
+
+		default:
+			return ""
+		}
+
+		setBool("udp-relay", opt.UDP)
+
+	case constant.Vmess:
+		var opt *outbound.VmessOption
+		switch x := p.clashOpt.(type) {
+		case outbound.VmessOption:
+			opt = &x
+		case *outbound.VmessOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "vmess", opt.Server, strconv.Itoa(opt.Port))
+		set("username", opt.UUID)
+		setBool("vmess-aead", opt.AlterID == 0)
+		setBool("tls", opt.TLS)
+		set("sni", opt.ServerName)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+
+		switch opt.Network {
+		case "", "tcp":
+		case "ws":
+			setBool("ws", true)
+			set("ws-path", opt.WSOpts.Path)
+			set("ws-headers", joinSurgeHeaders(opt.WSOpts.Headers))
+		default:
+			return ""
+		}
+
+	case constant.Trojan:
+		var opt *outbound.TrojanOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TrojanOption:
+			opt = &x
+		case *outbound.TrojanOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		if opt.RealityOpts.PublicKey != "" {
+			return ""
+		}
+
+		fields = append(fields, "trojan", opt.Server, strconv.Itoa(opt.Port))
+		set("password", opt.Password)
+		set("sni", opt.SNI)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+		setBool("udp-relay", opt.UDP)
+
+		switch opt.Network {
+		case "", "tcp":
+		case "ws":
+			setBool("ws", true)
+			set("ws-path", opt.WSOpts.Path)
+			set("ws-headers", joinSurgeHeaders(opt.WSOpts.Headers))
+		default:
+			return ""
+		}
+
+	case constant.Http:
+		var opt *outbound.HttpOption
+		switch x := p.clashOpt.(type) {
+		case outbound.HttpOption:
+			opt = &x
+		case *outbound.HttpOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		typ := "http"
+		if opt.TLS {
+			typ = "https"
+		}
+
+		fields = append(fields, typ, opt.Server, strconv.Itoa(opt.Port))
+		if opt.UserName != "" || opt.Password != "" {
+			fields = append(fields, opt.UserName, opt.Password)
+		}
+		set("sni", opt.SNI)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+
+	case constant.Socks5:
+		var opt *outbound.Socks5Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Socks5Option:
+			opt = &x
+		case *outbound.Socks5Option:
+			opt = x
+		default:
+			return ""
+		}
+
+		typ := "socks5"
+		if opt.TLS {
+			typ = "socks5-tls"
+		}
+
+		fields = append(fields, typ, opt.Server, strconv.Itoa(opt.Port))
+		if opt.UserName != "" || opt.Password != "" {
+			fields = append(fields, opt.UserName, opt.Password)
+		}
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+		setBool("udp-relay", opt.UDP)
+
+	case constant.Snell:
+		var opt *outbound.SnellOption
+		switch x := p.clashOpt.(type) {
+		case outbound.SnellOption:
+			opt = &x
+		case *outbound.SnellOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		fields = append(fields, "snell", opt.Server, strconv.Itoa(opt.Port))
+		set("psk", opt.Psk)
+		if opt.Version != 0 {
+			set("version", strconv.Itoa(opt.Version))
+		}
+		set("obfs", anyx.ToString(opt.ObfsOpts["mode"]))
+		set("obfs-host", anyx.ToString(opt.ObfsOpts["host"]))
+
+	case constant.Tuic:
+		var opt *outbound.TuicOption
+		switch x := p.clashOpt.(type) {
+		case outbound.TuicOption:
+			opt = &x
+		case *outbound.TuicOption:
+			opt = x
+		default:
+			return ""
+		}
+
+		if opt.Token != "" {
+			fields = append(fields, "tuic", opt.Server, strconv.Itoa(opt.Port))
+			set("token", opt.Token)
+		} else {
+			fields = append(fields, "tuic-v5", opt.Server, strconv.Itoa(opt.Port))
+			set("uuid", opt.UUID)
+			set("password", opt.Password)
+		}
+		set("alpn", strings.Join(opt.ALPN, ","))
+		set("sni", opt.SNI)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+
+	case constant.Hysteria2:
+		var opt *outbound.Hysteria2Option
+		switch x := p.clashOpt.(type) {
+		case outbound.Hysteria2Option:
+			opt = &x
+		case *outbound.Hysteria2Option:
+			opt = x
+		default:
+			return ""
+		}
+
+		// Surge 不支持 obfs
+		if opt.Obfs != "" {
+			return ""
+		}
+
+		fields = append(fields, "hysteria2", opt.Server, strconv.Itoa(opt.Port))
+		set("password", opt.Password)
+		set("sni", opt.SNI)
+		setBool("skip-cert-verify", opt.SkipCertVerify)
+		if down := outbound.StringToBps(opt.Down); down != 0 {
+			set("download-bandwidth", strconv.FormatUint(down/125000, 10))
+		}
+
+	default:
+		return ""
+	}
+
+	set("server-cert-fingerprint-sha256", anyx.ToString(p.opt["fingerprint"]))
+
+	return p.Name() + " = " + strings.Join(fields, ", ")
+}
+
+func joinSurgeHeaders(headers map[string]string) string {
+	var list []string
+	for _, key := range pie.Sort(maps.Keys(headers)) {
+		list = append(list, key+":"+headers[key])
+	}
+	return strings.Join(list, "|")
+}
@@ -0,0 +1,85 @@
+package adapter_test
+
+import (
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"reflect"
+	"testing"
+)
+
+func TestParseSurge(t *testing.T) {
+	tests := []struct {
+		name string
+		want map[string]any
+	}{
+		{
+			name: "ss = ss, 1.1.1.1, 8388, encrypt-method=aes-128-gcm, password=pwd, obfs=http, obfs-host=bing.com, udp-relay=true",
+			want: map[string]any{"type": "ss", "cipher": "aes-128-gcm", "password": "pwd", "udp": true, "plugin": "obfs",
+				"plugin-opts": map[string]any{"mode": "http", "host": "bing.com"}},
+		},
+		{
+			name: "shadow-tls = ss, 1.1.1.1, 443, encrypt-method=2022-blake3-aes-128-gcm, password=8JCsPssfgS8tiRwiMlhARg==, shadow-tls-password=pwd, shadow-tls-sni=www.bing.com, shadow-tls-version=3",
+			want: map[string]any{"type": "ss", "password": "8JCsPssfgS8tiRwiMlhARg==", "plugin": "shadow-tls",
+				"plugin-opts": map[string]any{"host": "www.bing.com", "password": "pwd", "version": 3}},
+		},
+		{
+			name: "vmess = vmess, 1.1.1.1, 443, username=7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d, ws=true, ws-path=/ws, ws-headers=Host:example.com, tls=true, sni=example.com, vmess-aead=true",
+			want: map[string]any{"type": "vmess", "uuid": "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d", "alterId": 0, "tls": true, "servername": "example.com",
+				"network": "ws", "ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "example.com"}}},
+		},
+		{
+			name: "trojan = trojan, 1.1.1.1, 443, password=pwd, sni=example.com, skip-cert-verify=true",
+			want: map[string]any{"type": "trojan", "password": "pwd", "sni": "example.com", "skip-cert-verify": true},
+		},
+		{
+			name: "https = https, 1.1.1.1, 443, user, pwd, sni=example.com",
+			want: map[string]any{"type": "http", "tls": true, "username": "user", "password": "pwd", "sni": "example.com"},
+		},
+		{
+			name: "socks5 = socks5, 1.1.1.1, 1080, user, pwd, udp-relay=true",
+			want: map[string]any{"type": "socks5", "username": "user", "password": "pwd", "udp": true},
+		},
+		{
+			name: "snell = snell, 1.1.1.1, 443, psk=psk, version=3, obfs=http, obfs-host=bing.com",
+			want: map[string]any{"type": "snell", "psk": "psk", "version": 3, "udp": true, "obfs-opts": map[string]any{"mode": "http", "host": "bing.com"}},
+		},
+		{
+			name: "tuic = tuic-v5, 1.1.1.1, 443, uuid=7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d, password=pwd, alpn=h3, sni=example.com",
+			want: map[string]any{"type": "tuic", "uuid": "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d", "password": "pwd", "alpn": []string{"h3"}, "sni": "example.com"},
+		},
+		{
+			name: "hy2 = hysteria2, 1.1.1.1, 443, password=pwd, sni=example.com, download-bandwidth=100",
+			want: map[string]any{"type": "hysteria2", "password": "pwd", "sni": "example.com", "down": "100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adapter.ParseSurge(tt.name)
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			m := got.ToClash()
+			for key, value := range tt.want {
+				if !reflect.DeepEqual(m[key], value) {
+					t.Errorf("%s = %#v, want %#v", key, m[key], value)
+				}
+			}
+
+			again, err := adapter.ParseSurge(got.ToSurge())
+			if err != nil {
+				t.Errorf("%s error = %v", got.ToSurge(), err)
+				return
+			}
+
+			if again.UniqueId() != got.UniqueId() || again.Name() != got.Name() {
+				t.Errorf("%s unique id mismatch", got.ToSurge())
+			}
+
+			detected, err := adapter.ParseProxyLine(tt.name)
+			if err != nil || detected.UniqueId() != got.UniqueId() {
+				t.Errorf("detect err:%v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/anyx"
+	"github.com/metacubex/mihomo/adapter/outbound"
+)
+
+type TlsVerify int
+
+const (
+	// TlsVerifyLink 以链接/配置中的声明为准，未声明时校验证书
+	TlsVerifyLink TlsVerify = iota
+	// TlsVerifyStrict 始终校验证书，忽略链接中的 allowInsecure 等参数
+	TlsVerifyStrict
+	// TlsVerifyInsecure 始终跳过证书校验
+	TlsVerifyInsecure
+)
+
+var (
+	ErrInvalidCA = errors.New("invalid ca")
+	// ErrCustomCAUnsupported 节点不支持单独配置 CA 或证书指纹
+	ErrCustomCAUnsupported = errors.New("custom ca unsupported")
+)
+
+func (o ParseOption) skipCertVerify(link bool) bool {
+	switch o.TlsVerify {
+	case TlsVerifyStrict:
+		return false
+	case TlsVerifyInsecure:
+		return true
+	default:
+		return link
+	}
+}
+
+// pinnedFingerprint 不支持 ca-str 的协议使用的证书指纹，Fingerprint 优先于 CustomCA
+func (o ParseOption) pinnedFingerprint() (string, error) {
+	if o.Fingerprint != "" || o.CustomCA == "" {
+		return o.Fingerprint, nil
+	}
+
+	var certs [][]byte
+	rest := []byte(o.CustomCA)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		_, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return "", ErrInvalidCA
+		}
+
+		certs = append(certs, block.Bytes)
+	}
+
+	switch len(certs) {
+	case 0:
+		return "", ErrInvalidCA
+	case 1:
+		sum := sha256.Sum256(certs[0])
+		return hex.EncodeToString(sum[:]), nil
+	default:
+		return "", fmt.Errorf("%w: only one certificate is allowed", ErrCustomCAUnsupported)
+	}
+}
+
+// applyTls 修改节点的证书校验配置，enabled 为节点是否使用 tls，caStr 为 nil 时协议不支持单独配置 CA
+func (o ParseOption) applyTls(enabled bool, skipCertVerify *bool, fingerprint *string, caStr *string) error {
+	*skipCertVerify = o.skipCertVerify(*skipCertVerify)
+
+	if !enabled {
+		return nil
+	}
+
+	if caStr != nil {
+		if o.CustomCA != "" {
+			*caStr = o.CustomCA
+		}
+		if o.Fingerprint != "" {
+			*fingerprint = o.Fingerprint
+		}
+		return nil
+	}
+
+	fp, err := o.pinnedFingerprint()
+	if err != nil {
+		return err
+	}
+	if fp != "" {
+		*fingerprint = fp
+	}
+
+	return nil
+}
+
+func (o ParseOption) applyTlsMap(m map[string]any, enabled bool, scopedCA bool) error {
+	skipCertVerify := anyx.ToBool(m["skip-cert-verify"])
+	fingerprint, _ := m["fingerprint"].(string)
+
+	var caStr *string
+	if scopedCA {
+		val, _ := m["ca-str"].(string)
+		caStr = &val
+	}
+
+	err := o.applyTls(enabled, &skipCertVerify, &fingerprint, caStr)
+	if err != nil {
+		return err
+	}
+
+	if skipCertVerify {
+		m["skip-cert-verify"] = true
+	} else {
+		delete(m, "skip-cert-verify")
+	}
+
+	if fingerprint != "" {
+		m["fingerprint"] = fingerprint
+	}
+
+	if caStr != nil && *caStr != "" {
+		m["ca-str"] = *caStr
+	}
+
+	return nil
+}
+
+// applyPlugin ss 插件中的 tls 配置，opts 为 nil 时会新建，返回修改后的 opts
+func (o ParseOption) applyPlugin(plugin string, opts map[string]any) (map[string]any, error) {
+	switch plugin {
+	case "v2ray-plugin":
+		if !anyx.ToBool(opts["tls"]) {
+			return opts, nil
+		}
+
+		// mihomo 的 v2ray-plugin 不支持证书指纹
+		if o.CustomCA != "" || o.Fingerprint != "" {
+			return nil, fmt.Errorf("%w: %s", ErrCustomCAUnsupported, plugin)
+		}
+
+	case "shadow-tls":
+	default:
+		return opts, nil
+	}
+
+	if opts == nil {
+		opts = map[string]any{}
+	}
+
+	err := o.applyTlsMap(opts, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+// apply 将 tls 相关的配置写入到各协议的配置中，ssr、snell、wireguard 以及 REALITY 节点不校验证书，不做修改
+func (o ParseOption) apply(opt any) error {
+	switch x := opt.(type) {
+	case map[string]any:
+		if reality, ok := x["reality-opts"].(map[string]any); ok && reality["public-key"] != nil && reality["public-key"] != "" {
+			return nil
+		}
+
+		switch x["type"] {
+		case "http", "https", "socks", "socks5", "vmess", "vless":
+			return o.applyTlsMap(x, anyx.ToBool(x["tls"]), false)
+		case "trojan":
+			return o.applyTlsMap(x, true, false)
+		case "hysteria", "hysteria2", "tuic":
+			return o.applyTlsMap(x, true, true)
+		case "ss", "shadowsocks":
+			plugin, _ := x["plugin"].(string)
+			pluginOpts, _ := x["plugin-opts"].(map[string]any)
+
+			pluginOpts, err := o.applyPlugin(plugin, pluginOpts)
+			if err != nil {
+				return err
+			}
+			if pluginOpts != nil {
+				x["plugin-opts"] = pluginOpts
+			}
+		}
+
+		return nil
+
+	case *outbound.HttpOption:
+		return o.applyTls(x.TLS, &x.SkipCertVerify, &x.Fingerprint, nil)
+
+	case *outbound.Socks5Option:
+		return o.applyTls(x.TLS, &x.SkipCertVerify, &x.Fingerprint, nil)
+
+	case *outbound.VmessOption:
+		if x.RealityOpts.PublicKey != "" {
+			return nil
+		}
+		return o.applyTls(x.TLS, &x.SkipCertVerify, &x.Fingerprint, nil)
+
+	case *outbound.VlessOption:
+		if x.RealityOpts.PublicKey != "" {
+			return nil
+		}
+		return o.applyTls(x.TLS, &x.SkipCertVerify, &x.Fingerprint, nil)
+
+	case *outbound.TrojanOption:
+		if x.RealityOpts.PublicKey != "" {
+			return nil
+		}
+		return o.applyTls(true, &x.SkipCertVerify, &x.Fingerprint, nil)
+
+	case *outbound.HysteriaOption:
+		return o.applyTls(true, &x.SkipCertVerify, &x.Fingerprint, &x.CustomCAString)
+
+	case *outbound.Hysteria2Option:
+		return o.applyTls(true, &x.SkipCertVerify, &x.Fingerprint, &x.CustomCAString)
+
+	case *outbound.TuicOption:
+		return o.applyTls(true, &x.SkipCertVerify, &x.Fingerprint, &x.CustomCAString)
+
+	case *outbound.ShadowSocksOption:
+		pluginOpts, err := o.applyPlugin(x.Plugin, x.PluginOpts)
+		if err != nil {
+			return err
+		}
+		x.PluginOpts = pluginOpts
+		return nil
+
+	default:
+		return nil
+	}
+}
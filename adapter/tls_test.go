@@ -0,0 +1,287 @@
+package adapter_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newConnectProxy 使用自签名证书的 https 代理
+func newConnectProxy() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer dst.Close()
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		if err != nil {
+			return
+		}
+
+		go io.Copy(dst, conn)
+		io.Copy(conn, dst)
+	}))
+}
+
+func TestParseOptionTlsVerify(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	proxy := newConnectProxy()
+	defer proxy.Close()
+
+	sum := sha256.Sum256(proxy.Certificate().Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+	bundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxy.Certificate().Raw}))
+
+	link := "https://" + proxy.Listener.Addr().String()
+
+	tests := []struct {
+		name    string
+		link    string
+		opt     adapter.ParseOption
+		wantErr bool
+	}{
+		{
+			name:    "link",
+			link:    link,
+			wantErr: true,
+		},
+		{
+			name: "link allowInsecure",
+			link: link + "?allowInsecure=1",
+		},
+		{
+			name:    "strict allowInsecure",
+			link:    link + "?allowInsecure=1",
+			opt:     adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict},
+			wantErr: true,
+		},
+		{
+			name: "insecure",
+			link: link,
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyInsecure},
+		},
+		{
+			name: "fingerprint",
+			link: link,
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict, Fingerprint: fingerprint},
+		},
+		{
+			name:    "fingerprint mismatch",
+			link:    link,
+			opt:     adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict, Fingerprint: strings.Repeat("0", 64)},
+			wantErr: true,
+		},
+		{
+			name: "custom ca",
+			link: link,
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict, CustomCA: bundle},
+		},
+		{
+			// CustomCA 只对当时解析的节点生效
+			name:    "link after custom ca",
+			link:    link,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := adapter.ParseLink(tt.link, tt.opt)
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			resp, err := node.GetClientWithTimeout(time.Second * 5).Get(target.URL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err:%v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+
+			buf, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Errorf("err:%v", err)
+				return
+			}
+
+			if string(buf) != "ok" {
+				t.Errorf("got %s", string(buf))
+			}
+		})
+	}
+}
+
+func TestParseOptionApply(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	srv.Close()
+
+	bundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	invalid := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("invalid")}))
+
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		link    string
+		opt     adapter.ParseOption
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "hysteria2://pwd@1.1.1.1:443?insecure=1#hy2",
+			want: map[string]any{"skip-cert-verify": true},
+		},
+		{
+			name: "hysteria2://pwd@1.1.1.1:443?insecure=1#hy2",
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict, CustomCA: bundle, Fingerprint: strings.Repeat("a", 64)},
+			want: map[string]any{"skip-cert-verify": nil, "ca-str": bundle, "fingerprint": strings.Repeat("a", 64)},
+		},
+		{
+			name: "tuic://token@1.1.1.1:443?allow_insecure=1#tuic",
+			want: map[string]any{"skip-cert-verify": true},
+		},
+		{
+			name: "hysteria://1.1.1.1:443?auth=a&insecure=1#hy",
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict},
+			want: map[string]any{"skip-cert-verify": nil},
+		},
+		{
+			name: "trojan://pwd@1.1.1.1:443#trojan",
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyInsecure},
+			want: map[string]any{"skip-cert-verify": true},
+		},
+		{
+			name: "socks5://1.1.1.1:1080?allowInsecure=true#socks",
+			want: map[string]any{"skip-cert-verify": true},
+		},
+		{
+			name:    "vless://uuid@1.1.1.1:443?security=tls#vless",
+			opt:     adapter.ParseOption{CustomCA: invalid},
+			wantErr: true,
+		},
+		{
+			name: "trojan://pwd@1.1.1.1:443#trojan",
+			opt:  adapter.ParseOption{CustomCA: bundle},
+			want: map[string]any{"fingerprint": fingerprint, "ca-str": nil},
+		},
+		{
+			name:    "trojan://pwd@1.1.1.1:443#trojan",
+			opt:     adapter.ParseOption{CustomCA: bundle + bundle},
+			wantErr: true,
+		},
+		{
+			// 未使用 tls 的节点不受 CustomCA 影响
+			name: "vless://uuid@1.1.1.1:443?security=none#vless",
+			opt:  adapter.ParseOption{CustomCA: bundle + bundle},
+			want: map[string]any{"fingerprint": nil},
+		},
+		{
+			// REALITY 节点的证书不固定，不受 CustomCA 影响
+			name: "vless://7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d@1.1.1.1:443?security=reality&sni=www.apple.com&fp=chrome&pbk=Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw&sid=6ba85179e30d4fc2&type=tcp#reality",
+			opt:  adapter.ParseOption{CustomCA: bundle + bundle},
+			want: map[string]any{"fingerprint": nil},
+		},
+		{
+			name: "clash reality",
+			link: "{type: vless, name: reality, server: 1.1.1.1, port: 443, uuid: 7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d, tls: true, servername: www.apple.com, client-fingerprint: chrome, reality-opts: {public-key: Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw, short-id: 6ba85179e30d4fc2}}",
+			opt:  adapter.ParseOption{CustomCA: bundle},
+			want: map[string]any{"fingerprint": nil},
+		},
+		{
+			name: "ss://YWVzLTEyOC1nY206cHdk@1.1.1.1:443/?plugin=v2ray-plugin%3Btls%3Bhost%3Dexample.com#ss",
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyInsecure},
+			want: map[string]any{"plugin-opts": map[string]any{"host": "example.com", "mode": "websocket", "skip-cert-verify": true, "tls": true}},
+		},
+		{
+			name:    "ss://YWVzLTEyOC1nY206cHdk@1.1.1.1:443/?plugin=v2ray-plugin%3Btls%3Bhost%3Dexample.com#ss",
+			opt:     adapter.ParseOption{CustomCA: bundle},
+			wantErr: true,
+		},
+		{
+			name: "ss://YWVzLTEyOC1nY206cHdk@1.1.1.1:443/?plugin=v2ray-plugin%3Bhost%3Dexample.com#ss",
+			opt:  adapter.ParseOption{CustomCA: bundle},
+			want: map[string]any{"plugin-opts": map[string]any{"host": "example.com", "mode": "websocket"}},
+		},
+		{
+			name: "ss://YWVzLTEyOC1nY206cHdk@1.1.1.1:443/?plugin=shadow-tls%3Bhost%3Dexample.com%3Bpassword%3Dpwd%3Bversion%3D2#ss",
+			opt:  adapter.ParseOption{CustomCA: bundle},
+			want: map[string]any{"plugin-opts": map[string]any{"fingerprint": fingerprint, "host": "example.com", "password": "pwd", "version": 2}},
+		},
+		{
+			name: "clash ss",
+			link: "{type: ss, name: ss, server: 1.1.1.1, port: 443, cipher: aes-128-gcm, password: pwd, plugin: v2ray-plugin, plugin-opts: {mode: websocket, tls: true, skip-cert-verify: true}}",
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict},
+			want: map[string]any{"plugin-opts": map[string]any{"mode": "websocket", "tls": true}},
+		},
+		{
+			name: "clash",
+			link: "{type: vmess, name: vmess, server: 1.1.1.1, port: 443, uuid: 7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d, alterId: 0, cipher: auto, tls: true, skip-cert-verify: true}",
+			opt:  adapter.ParseOption{TlsVerify: adapter.TlsVerifyStrict},
+			want: map[string]any{"skip-cert-verify": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := tt.link
+			if link == "" {
+				link = tt.name
+			}
+
+			node, err := adapter.ParseLink(link, tt.opt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err:%v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			m := node.ToClash()
+			for k, v := range tt.want {
+				if v == nil {
+					if val, ok := m[k]; ok && val != false {
+						t.Errorf("%s: got %v, want unset", k, val)
+					}
+					continue
+				}
+
+				if fmt.Sprint(m[k]) != fmt.Sprint(v) {
+					t.Errorf("%s: got %v, want %v", k, m[k], v)
+				}
+			}
+		})
+	}
+}
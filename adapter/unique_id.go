@@ -104,6 +104,9 @@ func (p *Adapter) updateUniqueId(src any) error {
 		case "hysteria":
 			opt = &outbound.HysteriaOption{}
 
+		case "hysteria2":
+			opt = &outbound.Hysteria2Option{}
+
 		case "wireguard":
 			opt = &outbound.WireGuardOption{}
 
@@ -224,6 +227,33 @@ func (p *Adapter) updateUniqueId(src any) error {
 						query.Set("obfs-header."+k, v)
 					}
 				}
+
+			case "shadow-tls":
+				o := shadowTlsOption{Version: 2}
+				err := decoder.Decode(opt.PluginOpts, &o)
+				if err != nil {
+					log.Errorf("err:%v", err)
+					return err
+				}
+
+				query.Set("obfs-host", o.Host)
+				query.Set("obfs-password", o.Password)
+				query.Set("obfs-version", strconv.Itoa(o.Version))
+
+			case "restls":
+				o := restlsOption{}
+				err := decoder.Decode(opt.PluginOpts, &o)
+				if err != nil {
+					log.Errorf("err:%v", err)
+					return err
+				}
+
+				query.Set("obfs-host", o.Host)
+				query.Set("obfs-password", o.Password)
+				query.Set("obfs-version-hint", o.VersionHint)
+				if o.RestlsScript != "" {
+					query.Set("obfs-restls-script", o.RestlsScript)
+				}
 			}
 		}
 
@@ -718,6 +748,62 @@ func (p *Adapter) updateUniqueId(src any) error {
 		p.vanillaLink = u.String()
 		p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
 
+	case constant.Hysteria2:
+		var opt *outbound.Hysteria2Option
+		switch x := o.(type) {
+		case outbound.Hysteria2Option:
+			opt = &x
+		case *outbound.Hysteria2Option:
+			opt = x
+		default:
+			return fmt.Errorf("invalid option type: %T", o)
+		}
+
+		u := &url.URL{
+			Scheme: "hysteria2",
+		}
+
+		query := u.Query()
+
+		if opt.Port > 0 {
+			u.Host = net.JoinHostPort(opt.Server, strconv.Itoa(opt.Port))
+		} else {
+			u.Host = opt.Server
+		}
+
+		if opt.Password != "" {
+			u.User = url.User(opt.Password)
+		}
+
+		if opt.Obfs != "" {
+			query.Set("obfs", opt.Obfs)
+		}
+
+		if opt.ObfsPassword != "" {
+			query.Set("obfs-password", opt.ObfsPassword)
+		}
+
+		if opt.SNI != "" {
+			query.Set("sni", opt.SNI)
+		}
+
+		if len(opt.ALPN) > 0 {
+			query.Set("alpn", strings.Join(pie.Sort(opt.ALPN), ","))
+		}
+
+		if opt.CustomCAString != "" {
+			query.Set("ca", opt.CustomCAString)
+		}
+
+		if opt.CWND != 0 {
+			query.Set("cwnd", strconv.Itoa(opt.CWND))
+		}
+
+		u.RawQuery = urlx.SortQuery(query).Encode()
+
+		p.vanillaLink = u.String()
+		p.uniqueId = fmt.Sprintf("%x", sha512.Sum512([]byte(p.vanillaLink)))
+
 	case constant.WireGuard:
 		var opt *outbound.WireGuardOption
 		switch x := o.(type) {
@@ -863,3 +949,16 @@ type v2rayObfsOption struct {
 	SkipCertVerify bool              `obfs:"skip-cert-verify,omitempty"`
 	Mux            bool              `obfs:"mux,omitempty"`
 }
+
+type shadowTlsOption struct {
+	Password string `obfs:"password"`
+	Host     string `obfs:"host"`
+	Version  int    `obfs:"version,omitempty"`
+}
+
+type restlsOption struct {
+	Password     string `obfs:"password"`
+	Host         string `obfs:"host"`
+	VersionHint  string `obfs:"version-hint"`
+	RestlsScript string `obfs:"restls-script,omitempty"`
+}
@@ -0,0 +1,249 @@
+package adapter
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/ice-cream-heaven/log"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultURLTestHistorySize = 10
+
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+// URLTestResult 一次测速的结果，各阶段的耗时均从发起请求开始计算，未经过的阶段为 0
+type URLTestResult struct {
+	URL    string
+	Status int
+
+	// Connect 通过节点建立到目标的连接，包含与节点的握手
+	Connect time.Duration
+	// TLSHandshake 与目标的 tls 握手，http 时为 0
+	TLSHandshake time.Duration
+	// FirstByte 收到响应的第一个字节
+	FirstByte time.Duration
+	// Total 读取完整个响应
+	Total time.Duration
+
+	Err error
+	At  time.Time
+}
+
+func (r *URLTestResult) Ok() bool {
+	return r.Err == nil
+}
+
+// URLTestHistory 最近的测速结果
+type URLTestHistory struct {
+	lock    sync.RWMutex
+	size    int
+	results []*URLTestResult
+}
+
+func newURLTestHistory(size int) *URLTestHistory {
+	return &URLTestHistory{
+		size: size,
+	}
+}
+
+func (h *URLTestHistory) add(result *URLTestResult) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.results = append(h.results, result)
+	if len(h.results) > h.size {
+		h.results = h.results[len(h.results)-h.size:]
+	}
+}
+
+// Resize 修改保留的结果数量，超出的旧结果会被丢弃
+func (h *URLTestHistory) Resize(size int) {
+	if size <= 0 {
+		size = DefaultURLTestHistorySize
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.size = size
+	if len(h.results) > h.size {
+		h.results = h.results[len(h.results)-h.size:]
+	}
+}
+
+// Results 从旧到新
+func (h *URLTestHistory) Results() []*URLTestResult {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return append([]*URLTestResult{}, h.results...)
+}
+
+// Last 没有测速过时为 nil
+func (h *URLTestHistory) Last() *URLTestResult {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if len(h.results) == 0 {
+		return nil
+	}
+	return h.results[len(h.results)-1]
+}
+
+// SuccessRate 成功的比例，没有测速过时为 0
+func (h *URLTestHistory) SuccessRate() float64 {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if len(h.results) == 0 {
+		return 0
+	}
+
+	var success int
+	for _, result := range h.results {
+		if result.Ok() {
+			success++
+		}
+	}
+
+	return float64(success) / float64(len(h.results))
+}
+
+func (h *URLTestHistory) latencies() []time.Duration {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	var list []time.Duration
+	for _, result := range h.results {
+		if result.Ok() {
+			list = append(list, result.Total)
+		}
+	}
+
+	return list
+}
+
+// Mean 成功的测速的平均总耗时
+func (h *URLTestHistory) Mean() time.Duration {
+	list := h.latencies()
+	if len(list) == 0 {
+		return 0
+	}
+
+	var sum time.Duration
+	for _, latency := range list {
+		sum += latency
+	}
+
+	return sum / time.Duration(len(list))
+}
+
+// P95 成功的测速的总耗时的 95 分位
+func (h *URLTestHistory) P95() time.Duration {
+	list := h.latencies()
+	if len(list) == 0 {
+		return 0
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i] < list[j]
+	})
+
+	return list[int(math.Ceil(float64(len(list))*0.95))-1]
+}
+
+// History 节点的测速记录，WithName 返回的节点共享同一份记录
+func (p *Adapter) History() *URLTestHistory {
+	return p.history
+}
+
+// URLTest 通过节点请求 url 并记录各阶段的耗时，expectedStatus 为 0 时 400 以下的状态码均视为成功。
+// 每次测速都会建立新的连接，结果会记录到 History 中
+func (p *Adapter) URLTest(ctx context.Context, url string, expectedStatus int) *URLTestResult {
+	result := &URLTestResult{
+		URL: url,
+		At:  time.Now(),
+	}
+
+	// 超时后连接可能仍在建立，各阶段的耗时通过原子操作记录
+	var connect, tlsHandshake, firstByte atomic.Int64
+	start := time.Now()
+	since := func() time.Duration {
+		return time.Since(start)
+	}
+
+	defer func() {
+		result.Connect = time.Duration(connect.Load())
+		result.TLSHandshake = time.Duration(tlsHandshake.Load())
+		result.FirstByte = time.Duration(firstByte.Load())
+		p.history.add(result)
+	}()
+
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tlsHandshake.Store(int64(since()))
+		},
+		GotFirstResponseByte: func() {
+			firstByte.Store(int64(since()))
+		},
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := p.HttpDialContext(ctx, network, addr)
+				connect.Store(int64(since()))
+				return conn, err
+			},
+			DisableKeepAlives: true,
+		},
+		// 重定向时只测第一个请求
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, url, nil)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		result.Err = err
+		return result
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Debugf("err:%v", err)
+		result.Err = err
+		result.Total = since()
+		return result
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	result.Total = since()
+	result.Status = resp.StatusCode
+	if err != nil {
+		log.Debugf("err:%v", err)
+		result.Err = err
+		return result
+	}
+
+	switch {
+	case expectedStatus == 0 && resp.StatusCode >= http.StatusBadRequest,
+		expectedStatus != 0 && resp.StatusCode != expectedStatus:
+		result.Err = fmt.Errorf("%w:%d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return result
+}
@@ -0,0 +1,199 @@
+package adapter_test
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// socks5Proxy 只支持无认证的 CONNECT，记录建立的连接数，delay 为连接目标前等待的时间
+type socks5Proxy struct {
+	net.Listener
+	conns atomic.Int32
+	delay atomic.Int64
+}
+
+func newSocks5Proxy(t testing.TB) *socks5Proxy {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	proxy := &socks5Proxy{Listener: l}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			proxy.conns.Add(1)
+			go proxy.serve(conn)
+		}
+	}()
+
+	return proxy
+}
+
+func (s *socks5Proxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	buf := make([]byte, 262)
+
+	// 版本以及认证方式
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return
+	}
+
+	// VER CMD RSV ATYP
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+
+	var host string
+	switch buf[3] {
+	case 1:
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return
+		}
+		host = net.IP(buf[:4]).String()
+	case 3:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return
+		}
+		n := buf[0]
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return
+		}
+		host = string(buf[:n])
+	case 4:
+		if _, err := io.ReadFull(conn, buf[:16]); err != nil {
+			return
+		}
+		host = net.IP(buf[:16]).String()
+	default:
+		return
+	}
+
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	port := binary.BigEndian.Uint16(buf[:2])
+
+	time.Sleep(time.Duration(s.delay.Load()))
+
+	dst, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer dst.Close()
+
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+
+	go func() {
+		_, _ = io.Copy(dst, conn)
+		_ = dst.Close()
+	}()
+	_, _ = io.Copy(conn, dst)
+}
+
+func (s *socks5Proxy) Adapter(t testing.TB) *adapter.Adapter {
+	node, err := adapter.ParseLink("socks5://" + s.Addr().String() + "#socks")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	return node
+}
+
+func TestURLTest(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 10)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	proxy := newSocks5Proxy(t)
+	node := proxy.Adapter(t)
+	ctx := context.Background()
+
+	result := node.URLTest(ctx, target.URL, http.StatusNoContent)
+	if !result.Ok() || result.Status != http.StatusNoContent {
+		t.Fatalf("result = %+v", result)
+	}
+
+	if result.Connect <= 0 || result.FirstByte < result.Connect || result.Total < result.FirstByte || result.TLSHandshake != 0 {
+		t.Errorf("timings = %+v", result)
+	}
+
+	if proxy.conns.Load() != 1 {
+		t.Errorf("proxy conns = %d", proxy.conns.Load())
+	}
+
+	// 状态码不符合预期
+	if result := node.URLTest(ctx, target.URL, http.StatusOK); result.Ok() || result.Status != http.StatusNoContent {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 证书校验失败，但仍然记录了 tls 握手的耗时
+	tlsTarget := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsTarget.Close()
+	if result := node.URLTest(ctx, tlsTarget.URL, 0); result.Ok() || result.TLSHandshake <= 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 超时
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 500)
+	}))
+	defer slow.Close()
+
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer cancel()
+	if result := node.URLTest(timeout, slow.URL, 0); result.Ok() {
+		t.Errorf("result = %+v", result)
+	}
+
+	history := node.History()
+	if len(history.Results()) != 4 || history.Last().URL != slow.URL {
+		t.Fatalf("history = %v", history.Results())
+	}
+
+	if history.SuccessRate() != 0.25 {
+		t.Errorf("success rate = %v", history.SuccessRate())
+	}
+
+	if history.Mean() != result.Total || history.P95() != result.Total {
+		t.Errorf("mean:%v p95:%v want %v", history.Mean(), history.P95(), result.Total)
+	}
+
+	// 重命名后共享记录
+	if node.WithName("renamed").History() != history {
+		t.Errorf("history should be shared")
+	}
+
+	history.Resize(2)
+	if len(history.Results()) != 2 || history.SuccessRate() != 0 || history.Mean() != 0 {
+		t.Errorf("history = %v", history.Results())
+	}
+}
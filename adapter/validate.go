@@ -0,0 +1,369 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/elliotchance/pie/v2"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidField = errors.New("invalid field")
+
+// FieldError clash 配置中某个字段校验失败，Path 形如 port、alpn[1]、peers[0].server
+type FieldError struct {
+	Path   string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Reason
+}
+
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidField
+}
+
+// FieldErrors 一个节点中所有校验失败的字段，可以通过 errors.As 取出第一个 *FieldError
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	return strings.Join(pie.Map(e, func(err *FieldError) string {
+		return err.Error()
+	}), "; ")
+}
+
+func (e FieldErrors) Unwrap() []error {
+	return pie.Map(e, func(err *FieldError) error {
+		return err
+	})
+}
+
+var (
+	clashTypeAlias = map[string]string{
+		"shadowsocks":  "ss",
+		"shadowsocksr": "ssr",
+		"socks":        "socks5",
+		"hy":           "hysteria",
+		"hy2":          "hysteria2",
+		"wg":           "wireguard",
+	}
+
+	// 同一个字段的其他写法，key 为 mihomo 使用的名称
+	clashFieldAlias = map[string][]string{
+		"alterId": {"alter-id", "alterid", "alter_id"},
+	}
+
+	// sing-shadowsocks2 支持的加密方式
+	ssCiphers = []string{
+		"none",
+		"aes-128-gcm", "aes-192-gcm", "aes-256-gcm",
+		"chacha20-ietf-poly1305", "xchacha20-ietf-poly1305",
+		"rabbit128-poly1305", "aes-128-gcm-siv", "aes-256-gcm-siv",
+		"aegis-128l", "aegis-256", "aez-384", "deoxys-ii-256-128",
+		"lea-128-gcm", "lea-192-gcm", "lea-256-gcm",
+		"2022-blake3-aes-128-gcm", "2022-blake3-aes-256-gcm", "2022-blake3-chacha20-poly1305",
+		"aes-128-ctr", "aes-192-ctr", "aes-256-ctr",
+		"aes-128-cfb", "aes-192-cfb", "aes-256-cfb",
+		"rc4-md5", "chacha20-ietf", "xchacha20", "chacha20",
+	}
+
+	vmessCiphers = []string{"auto", "none", "zero", "aes-128-gcm", "chacha20-poly1305"}
+
+	uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// normalizeClash 统一协议类型以及字段的别名
+func normalizeClash(m map[string]any) {
+	if t, ok := m["type"].(string); ok {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if alias, ok := clashTypeAlias[t]; ok {
+			t = alias
+		}
+		m["type"] = t
+	}
+
+	for key, aliases := range clashFieldAlias {
+		for _, alias := range aliases {
+			value, ok := m[alias]
+			if !ok {
+				continue
+			}
+
+			delete(m, alias)
+			if _, ok := m[key]; !ok {
+				m[key] = value
+			}
+		}
+	}
+}
+
+// ValidateClash 统一别名后按协议校验必填字段、端口、uuid 以及加密方式，
+// 返回的错误为 FieldErrors，未知的协议不做校验
+func ValidateClash(m map[string]any) error {
+	normalizeClash(m)
+
+	v := &clashValidator{m: m}
+
+	if name, ok := m["name"]; ok {
+		if _, ok := scalarString(name); !ok {
+			v.fail("name", "must be a string")
+		}
+	}
+
+	typ, ok := m["type"].(string)
+	if !ok || typ == "" {
+		v.fail("type", "is required")
+		return v.err()
+	}
+
+	switch typ {
+	case "direct", "reject", "dns":
+		return v.err()
+	case "wireguard":
+		v.string("private-key")
+		if peers, ok := m["peers"].([]any); ok && len(peers) > 0 {
+			for i, peer := range peers {
+				pm, ok := peer.(map[string]any)
+				if !ok {
+					v.fail(fmt.Sprintf("peers[%d]", i), "must be a map")
+					continue
+				}
+
+				pv := &clashValidator{m: pm, prefix: fmt.Sprintf("peers[%d].", i)}
+				pv.server()
+				pv.string("public-key")
+				v.errs = append(v.errs, pv.errs...)
+			}
+			return v.err()
+		}
+
+		v.server()
+		v.string("public-key")
+		return v.err()
+	}
+
+	v.server()
+	v.alpn()
+
+	switch typ {
+	case "ss":
+		v.oneOf("cipher", ssCiphers)
+		v.string("password")
+	case "ssr":
+		v.string("cipher")
+		v.string("password")
+		v.string("obfs")
+		v.string("protocol")
+	case "vmess":
+		v.uuid("uuid", true)
+		if _, ok := m["cipher"]; ok {
+			v.oneOf("cipher", vmessCiphers)
+		}
+		if _, ok := m["alterId"]; ok {
+			v.integer("alterId", 0, math.MaxUint16)
+		}
+	case "vless":
+		v.uuid("uuid", true)
+	case "trojan":
+		v.string("password")
+	case "snell":
+		v.string("psk")
+		if _, ok := m["version"]; ok {
+			v.integer("version", 1, 3)
+		}
+	case "hysteria":
+		if _, ok := m["up-speed"]; !ok {
+			v.string("up")
+		}
+		if _, ok := m["down-speed"]; !ok {
+			v.string("down")
+		}
+	case "tuic":
+		// v4 使用 token，v5 使用 uuid 以及 password
+		if _, ok := m["token"]; !ok {
+			v.uuid("uuid", false)
+			v.string("password")
+		}
+	}
+
+	return v.err()
+}
+
+type clashValidator struct {
+	m      map[string]any
+	prefix string
+	errs   FieldErrors
+}
+
+func (v *clashValidator) fail(path, reason string) {
+	v.errs = append(v.errs, &FieldError{
+		Path:   v.prefix + path,
+		Reason: reason,
+	})
+}
+
+func (v *clashValidator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *clashValidator) string(key string) (string, bool) {
+	value, ok := v.m[key]
+	if !ok || value == nil {
+		v.fail(key, "is required")
+		return "", false
+	}
+
+	s, ok := scalarString(value)
+	if !ok {
+		v.fail(key, "must be a string")
+		return "", false
+	}
+
+	if strings.TrimSpace(s) == "" {
+		v.fail(key, "is required")
+		return "", false
+	}
+
+	return s, true
+}
+
+func (v *clashValidator) integer(key string, min, max int64) {
+	value, ok := v.m[key]
+	if !ok || value == nil {
+		v.fail(key, "is required")
+		return
+	}
+
+	var i int64
+	switch x := value.(type) {
+	case int:
+		i = int64(x)
+	case int64:
+		i = x
+	case uint64:
+		if x > math.MaxInt64 {
+			i = math.MaxInt64
+		} else {
+			i = int64(x)
+		}
+	case float64:
+		if x != math.Trunc(x) {
+			v.fail(key, "must be an integer")
+			return
+		}
+		i = int64(x)
+	case string:
+		var err error
+		i, err = strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+		if err != nil {
+			v.fail(key, "must be an integer")
+			return
+		}
+	default:
+		v.fail(key, "must be an integer")
+		return
+	}
+
+	if i < min || i > max {
+		v.fail(key, fmt.Sprintf("must be between %d and %d", min, max))
+	}
+}
+
+func (v *clashValidator) server() {
+	v.string("server")
+
+	// hysteria、hysteria2 可以使用 ports 端口跳跃
+	if _, ok := v.m["ports"]; ok {
+		if _, ok := v.m["port"]; !ok {
+			return
+		}
+	}
+
+	v.integer("port", 1, math.MaxUint16)
+}
+
+func (v *clashValidator) oneOf(key string, values []string) {
+	s, ok := v.string(key)
+	if !ok {
+		return
+	}
+
+	if !pie.Contains(values, strings.ToLower(s)) {
+		v.fail(key, fmt.Sprintf("unsupported value %q", s))
+	}
+}
+
+// uuid 为 true 时与 xray 一致，允许不超过 30 个字节的任意字符串，会被映射为 uuid
+func (v *clashValidator) uuid(key string, mapping bool) {
+	s, ok := v.string(key)
+	if !ok {
+		return
+	}
+
+	if uuidRegexp.MatchString(s) {
+		return
+	}
+
+	if mapping && len(s) <= 30 {
+		return
+	}
+
+	v.fail(key, fmt.Sprintf("invalid uuid %q", s))
+}
+
+func (v *clashValidator) alpn() {
+	value, ok := v.m["alpn"]
+	if !ok || value == nil {
+		return
+	}
+
+	var list []any
+	switch x := value.(type) {
+	case []any:
+		list = x
+	case []string:
+		list = pie.Map(x, func(s string) any {
+			return s
+		})
+	default:
+		v.fail("alpn", "must be a list")
+		return
+	}
+
+	// NOTE: alpn 的取值由各协议自行决定（如 hysteria、grpc-exp），只要求非空
+	for i, item := range list {
+		s, ok := scalarString(item)
+		if !ok {
+			v.fail(fmt.Sprintf("alpn[%d]", i), "must be a string")
+			continue
+		}
+
+		if strings.TrimSpace(s) == "" {
+			v.fail(fmt.Sprintf("alpn[%d]", i), "is required")
+		}
+	}
+}
+
+// scalarString 与 mihomo 的 WeaklyTypedInput 一致，数字可以作为字符串
+func scalarString(value any) (string, bool) {
+	switch x := value.(type) {
+	case string:
+		return x, true
+	case int:
+		return strconv.Itoa(x), true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	case uint64:
+		return strconv.FormatUint(x, 10), true
+	case float64:
+		return strconv.FormatFloat(x, 'E', -1, 64), true
+	default:
+		return "", false
+	}
+}
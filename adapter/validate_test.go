@@ -0,0 +1,164 @@
+package adapter_test
+
+import (
+	"errors"
+	"github.com/ice-cream-heaven/vanilla/adapter"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+)
+
+func TestParseClashValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []adapter.FieldError
+	}{
+		{
+			name:   "ss",
+			config: `{name: ss, type: shadowsocks, server: 1.1.1.1, port: 8388, cipher: aes-128-gcm, password: pwd}`,
+		},
+		{
+			name:   "socks alias",
+			config: `{name: socks, type: socks, server: 1.1.1.1, port: "1080"}`,
+		},
+		{
+			name:   "hy2 alias",
+			config: `{name: hy2, type: hy2, server: 1.1.1.1, port: 443, password: pwd, alpn: [h3]}`,
+		},
+		{
+			name:   "vmess alter-id",
+			config: `{name: vmess, type: vmess, server: 1.1.1.1, port: 443, uuid: 7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d, cipher: auto, alter-id: 0}`,
+		},
+		{
+			name:   "vless mapped uuid",
+			config: `{name: vless, type: vless, server: 1.1.1.1, port: 443, uuid: my-custom-id}`,
+		},
+		{
+			name:   "missing server",
+			config: `{name: trojan, type: trojan, port: 443, password: pwd}`,
+			want:   []adapter.FieldError{{Path: "server", Reason: "is required"}},
+		},
+		{
+			name:   "port range",
+			config: `{name: trojan, type: trojan, server: 1.1.1.1, port: 70000, password: pwd}`,
+			want:   []adapter.FieldError{{Path: "port", Reason: "must be between 1 and 65535"}},
+		},
+		{
+			name:   "cipher",
+			config: `{name: ss, type: ss, server: 1.1.1.1, port: 8388, cipher: aes-512-gcm, password: pwd}`,
+			want:   []adapter.FieldError{{Path: "cipher", Reason: `unsupported value "aes-512-gcm"`}},
+		},
+		{
+			name:   "uuid",
+			config: `{name: tuic, type: tuic, server: 1.1.1.1, port: 443, uuid: not-a-uuid, password: pwd}`,
+			want:   []adapter.FieldError{{Path: "uuid", Reason: `invalid uuid "not-a-uuid"`}},
+		},
+		{
+			name:   "numeric password",
+			config: `{name: trojan, type: trojan, server: 1.1.1.1, port: 443, password: 12345678}`,
+		},
+		{
+			name:   "ss numeric password",
+			config: `{name: ss, type: ss, server: 1.1.1.1, port: 8388, cipher: aes-128-gcm, password: 12345678}`,
+		},
+		{
+			name:   "numeric name",
+			config: `{name: 123, type: trojan, server: 1.1.1.1, port: 443, password: pwd}`,
+		},
+		{
+			name:   "hysteria numeric speed",
+			config: `{name: hy, type: hysteria, server: 1.1.1.1, port: 443, auth-str: pwd, up: 100, down: 100, alpn: [hysteria]}`,
+		},
+		{
+			name:   "grpc-exp alpn",
+			config: `{name: trojan, type: trojan, server: 1.1.1.1, port: 443, password: pwd, alpn: [h2, grpc-exp]}`,
+		},
+		{
+			name:   "alpn",
+			config: `{name: trojan, type: trojan, server: 1.1.1.1, port: 443, password: pwd, alpn: [h2, ""]}`,
+			want:   []adapter.FieldError{{Path: "alpn[1]", Reason: "is required"}},
+		},
+		{
+			name:   "bool password",
+			config: `{name: trojan, type: trojan, server: 1.1.1.1, port: 443, password: true}`,
+			want:   []adapter.FieldError{{Path: "password", Reason: "must be a string"}},
+		},
+		{
+			name:   "wireguard peers",
+			config: `{name: wg, type: wg, private-key: key, peers: [{server: 1.1.1.1, port: 0, public-key: key}]}`,
+			want:   []adapter.FieldError{{Path: "peers[0].port", Reason: "must be between 1 and 65535"}},
+		},
+		{
+			name:   "multiple",
+			config: `{name: vmess, type: vmess, server: "", port: 443, cipher: rc4}`,
+			want: []adapter.FieldError{
+				{Path: "server", Reason: "is required"},
+				{Path: "uuid", Reason: "is required"},
+				{Path: "cipher", Reason: `unsupported value "rc4"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m map[string]any
+			err := yaml.Unmarshal([]byte(tt.config), &m)
+			if err != nil {
+				t.Fatalf("err:%v", err)
+			}
+
+			_, err = adapter.ParseClash(m)
+
+			if tt.want == nil {
+				var fieldErr *adapter.FieldError
+				if errors.As(err, &fieldErr) {
+					t.Errorf("unexpected field error:%v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, adapter.ErrInvalidField) {
+				t.Fatalf("err = %v, want ErrInvalidField", err)
+			}
+
+			var errs adapter.FieldErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("err = %T", err)
+			}
+
+			var got []adapter.FieldError
+			for _, e := range errs {
+				got = append(got, *e)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateClashAlias(t *testing.T) {
+	m := map[string]any{
+		"name":     "vmess",
+		"type":     "VMess",
+		"server":   "1.1.1.1",
+		"port":     443,
+		"uuid":     "7b9a6ae4-1d0e-4b6e-9c0f-3f6a0b8e0c1d",
+		"alter-id": 0,
+	}
+
+	err := adapter.ValidateClash(m)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if m["type"] != "vmess" || m["alterId"] != 0 {
+		t.Errorf("m = %v", m)
+	}
+
+	if _, ok := m["alter-id"]; ok {
+		t.Errorf("alter-id should be removed: %v", m)
+	}
+}
@@ -0,0 +1,424 @@
+package adapter
+
+import (
+	"github.com/elliotchance/pie/v2"
+	"github.com/ice-cream-heaven/log"
+	"github.com/ice-cream-heaven/utils/json"
+)
+
+type xrayUser struct {
+	ID       string `json:"id"`
+	AlterID  int    `json:"alterId"`
+	Security string `json:"security"`
+	Flow     string `json:"flow"`
+	User     string `json:"user"`
+	Pass     string `json:"pass"`
+}
+
+type xrayServer struct {
+	Address  string      `json:"address"`
+	Port     int         `json:"port"`
+	Password string      `json:"password"`
+	Method   string      `json:"method"`
+	Flow     string      `json:"flow"`
+	Users    []*xrayUser `json:"users"`
+}
+
+type xrayTLSSettings struct {
+	ServerName    string   `json:"serverName"`
+	AllowInsecure bool     `json:"allowInsecure"`
+	ALPN          []string `json:"alpn"`
+	Fingerprint   string   `json:"fingerprint"`
+}
+
+type xrayRealitySettings struct {
+	ServerName  string `json:"serverName"`
+	Fingerprint string `json:"fingerprint"`
+	PublicKey   string `json:"publicKey"`
+	ShortID     string `json:"shortId"`
+}
+
+type xrayTCPSettings struct {
+	Header struct {
+		Type    string `json:"type"`
+		Request struct {
+			Method  string                 `json:"method"`
+			Path    singBoxList            `json:"path"`
+			Headers map[string]singBoxList `json:"headers"`
+		} `json:"request"`
+	} `json:"header"`
+}
+
+type xrayWSSettings struct {
+	Path    string            `json:"path"`
+	Host    string            `json:"host"`
+	Headers map[string]string `json:"headers"`
+}
+
+type xrayStreamSettings struct {
+	Network         string               `json:"network"`
+	Security        string               `json:"security"`
+	TLSSettings     *xrayTLSSettings     `json:"tlsSettings"`
+	XTLSSettings    *xrayTLSSettings     `json:"xtlsSettings"`
+	RealitySettings *xrayRealitySettings `json:"realitySettings"`
+
+	TCPSettings         *xrayTCPSettings `json:"tcpSettings"`
+	RawSettings         *xrayTCPSettings `json:"rawSettings"`
+	WSSettings          *xrayWSSettings  `json:"wsSettings"`
+	HTTPUpgradeSettings *xrayWSSettings  `json:"httpupgradeSettings"`
+	HTTPSettings        *struct {
+		Host singBoxList `json:"host"`
+		Path string      `json:"path"`
+	} `json:"httpSettings"`
+	GRPCSettings *struct {
+		ServiceName string `json:"serviceName"`
+	} `json:"grpcSettings"`
+}
+
+// xrayOutbound V2Ray / Xray 的 outbound，只包含可以转换为 clash 的字段
+// https://xtls.github.io/config/outbound.html
+type xrayOutbound struct {
+	Protocol string `json:"protocol"`
+	Tag      string `json:"tag"`
+	Settings struct {
+		Vnext   []*xrayServer `json:"vnext"`
+		Servers []*xrayServer `json:"servers"`
+	} `json:"settings"`
+	StreamSettings *xrayStreamSettings `json:"streamSettings"`
+}
+
+// ParseXray 解析 V2Ray / Xray 的客户端配置，无法转换的 outbound 会被忽略
+func ParseXray(b []byte, opts ...ParseOption) ([]*Adapter, error) {
+	report, err := parseXray(b, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Adapters, nil
+}
+
+func parseXray(b []byte, opts ...ParseOption) (*SubscriptionReport, error) {
+	var c struct {
+		Outbounds []*xrayOutbound `json:"outbounds"`
+	}
+	err := json.Unmarshal(b, &c)
+	if err != nil {
+		log.Debugf("err:%v", err)
+		return nil, err
+	}
+
+	// sing-box 的配置同样有 outbounds，通过 protocol 区分
+	if !pie.Any(c.Outbounds, func(o *xrayOutbound) bool { return o.Protocol != "" }) {
+		return nil, ErrUnsupportedType
+	}
+
+	report := newSubscriptionReport(SubscriptionFormatXray)
+	for i, o := range c.Outbounds {
+		switch o.Protocol {
+		case "freedom", "blackhole", "dns", "loopback":
+			continue
+		}
+
+		servers := o.Settings.Servers
+		if o.Protocol == "vmess" || o.Protocol == "vless" {
+			servers = o.Settings.Vnext
+		}
+
+		if len(servers) == 0 {
+			log.Debugf("unsupported xray outbound protocol:%s", o.Protocol)
+			report.reject(0, i, o, ErrUnsupportedType)
+			continue
+		}
+
+		for _, server := range servers {
+			m, err := o.toClash(server)
+			if err != nil {
+				report.reject(0, i, o, err)
+				continue
+			}
+
+			node, err := ParseClash(m, opts...)
+			if err != nil {
+				report.reject(0, i, o, err)
+				continue
+			}
+
+			report.accept(node)
+		}
+	}
+
+	return report, nil
+}
+
+// transportHost 返回传输层配置中的 Host，对应分享链接中的 host
+func transportHost(m map[string]any) string {
+	if opts, ok := m["ws-opts"].(map[string]any); ok {
+		if headers, ok := opts["headers"].(map[string]any); ok {
+			host, _ := headers["Host"].(string)
+			return host
+		}
+	}
+
+	if opts, ok := m["h2-opts"].(map[string]any); ok {
+		if hosts, ok := opts["host"].([]string); ok && len(hosts) > 0 {
+			return hosts[0]
+		}
+	}
+
+	if opts, ok := m["http-opts"].(map[string]any); ok {
+		if headers, ok := opts["headers"].(map[string]any); ok {
+			if hosts, ok := headers["Host"].([]string); ok && len(hosts) > 0 {
+				return hosts[0]
+			}
+		}
+	}
+
+	return ""
+}
+
+func (o *xrayOutbound) toClash(server *xrayServer) (map[string]any, error) {
+	m := map[string]any{
+		"name":   o.Tag,
+		"server": server.Address,
+		"port":   server.Port,
+		"udp":    true,
+	}
+
+	user := &xrayUser{}
+	if len(server.Users) > 0 {
+		user = server.Users[0]
+	}
+
+	switch o.Protocol {
+	case "vmess":
+		m["type"] = "vmess"
+		m["uuid"] = user.ID
+		m["alterId"] = user.AlterID
+		m["cipher"] = user.Security
+		if user.Security == "" {
+			m["cipher"] = "auto"
+		}
+		if user.Security == "zero" {
+			m["cipher"] = "none"
+		}
+		o.tlsToClash(m, "servername")
+		if !o.transportToClash(m) {
+			return nil, ErrUnsupportedType
+		}
+
+	case "vless":
+		m["type"] = "vless"
+		m["uuid"] = user.ID
+		m["flow"] = user.Flow
+		o.tlsToClash(m, "servername")
+		if !o.transportToClash(m) {
+			return nil, ErrUnsupportedType
+		}
+
+		// 与 ParseLinkVless 保持一致，开启 tls 时默认使用 Host 作为 servername
+		if (m["servername"] == nil || m["servername"] == "") && m["tls"] == true {
+			if host := transportHost(m); host != "" {
+				m["servername"] = host
+			}
+		}
+
+		// 与 ParseLinkVless 保持一致，tcp 需要显式声明
+		if m["network"] == nil {
+			m["network"] = "tcp"
+		}
+
+		switch user.Flow {
+		case "", "xtls-rprx-vision", "xtls-rprx-vision-udp443":
+		default:
+			log.Errorf("unsupported flow:%s", user.Flow)
+			return nil, ErrUnsupportedType
+		}
+
+	case "trojan":
+		m["type"] = "trojan"
+		m["password"] = server.Password
+		o.tlsToClash(m, "sni")
+		delete(m, "tls")
+		if !o.transportToClash(m) {
+			return nil, ErrUnsupportedType
+		}
+
+		// 与 ParseLinkTrojan 保持一致，默认依次使用 ws 的 Host、服务器地址作为 sni
+		if m["sni"] == nil || m["sni"] == "" {
+			m["sni"] = transportHost(m)
+		}
+		if m["sni"] == nil || m["sni"] == "" {
+			m["sni"] = server.Address
+		}
+
+		switch m["network"] {
+		case nil, "ws", "grpc":
+		default:
+			log.Errorf("unsupported trojan network:%v", m["network"])
+			return nil, ErrUnsupportedType
+		}
+
+	case "shadowsocks":
+		m["type"] = "ss"
+		m["cipher"] = server.Method
+		m["password"] = server.Password
+
+	case "socks":
+		m["type"] = "socks5"
+		m["username"] = user.User
+		m["password"] = user.Pass
+		o.tlsToClash(m, "sni")
+		delete(m, "sni")
+		delete(m, "alpn")
+
+	case "http":
+		m["type"] = "http"
+		m["username"] = user.User
+		m["password"] = user.Pass
+		o.tlsToClash(m, "sni")
+
+	default:
+		log.Debugf("unsupported xray outbound protocol:%s", o.Protocol)
+		return nil, ErrUnsupportedType
+	}
+
+	return compactClash(m), nil
+}
+
+func (o *xrayOutbound) tlsToClash(m map[string]any, sniKey string) {
+	s := o.StreamSettings
+	if s == nil {
+		return
+	}
+
+	switch s.Security {
+	case "tls", "xtls":
+		m["tls"] = true
+
+		t := s.TLSSettings
+		if s.Security == "xtls" && s.XTLSSettings != nil {
+			t = s.XTLSSettings
+		}
+		if t == nil {
+			return
+		}
+
+		m[sniKey] = t.ServerName
+		m["skip-cert-verify"] = t.AllowInsecure
+		m["client-fingerprint"] = t.Fingerprint
+		if len(t.ALPN) > 0 {
+			m["alpn"] = t.ALPN
+		}
+
+	case "reality":
+		m["tls"] = true
+
+		r := s.RealitySettings
+		if r == nil {
+			return
+		}
+
+		m[sniKey] = r.ServerName
+		m["client-fingerprint"] = r.Fingerprint
+		m["reality-opts"] = map[string]any{
+			"public-key": r.PublicKey,
+			"short-id":   r.ShortID,
+		}
+	}
+}
+
+func (o *xrayOutbound) transportToClash(m map[string]any) bool {
+	s := o.StreamSettings
+	if s == nil {
+		return true
+	}
+
+	switch s.Network {
+	case "", "tcp", "raw":
+		t := s.TCPSettings
+		if s.RawSettings != nil {
+			t = s.RawSettings
+		}
+
+		if t == nil || t.Header.Type != "http" {
+			return true
+		}
+
+		headers := map[string]any{}
+		for key, value := range t.Header.Request.Headers {
+			headers[key] = []string(value)
+		}
+
+		method := t.Header.Request.Method
+		if method == "" {
+			method = "GET"
+		}
+
+		m["network"] = "http"
+		m["http-opts"] = map[string]any{
+			"method":  method,
+			"path":    []string(t.Header.Request.Path),
+			"headers": headers,
+		}
+
+	case "ws", "httpupgrade":
+		t := s.WSSettings
+		if s.Network == "httpupgrade" {
+			t = s.HTTPUpgradeSettings
+		}
+		if t == nil {
+			t = &xrayWSSettings{}
+		}
+
+		wsOpts := map[string]any{}
+		path, ed := parseEarlyData(t.Path)
+		wsOpts["path"] = path
+		if ed > 0 {
+			wsOpts["max-early-data"] = ed
+			wsOpts["early-data-header-name"] = "Sec-WebSocket-Protocol"
+		}
+
+		if s.Network == "httpupgrade" {
+			wsOpts["v2ray-http-upgrade"] = true
+		}
+
+		headers := map[string]any{}
+		for key, value := range t.Headers {
+			headers[key] = value
+		}
+		if t.Host != "" {
+			headers["Host"] = t.Host
+		}
+		if len(headers) > 0 {
+			wsOpts["headers"] = headers
+		}
+
+		m["network"] = "ws"
+		m["ws-opts"] = wsOpts
+
+	case "h2", "http":
+		h2Opts := map[string]any{}
+		if t := s.HTTPSettings; t != nil {
+			h2Opts["host"] = []string(t.Host)
+			h2Opts["path"] = t.Path
+		}
+
+		m["network"] = "h2"
+		m["h2-opts"] = h2Opts
+
+	case "grpc":
+		grpcOpts := map[string]any{}
+		if t := s.GRPCSettings; t != nil {
+			grpcOpts["grpc-service-name"] = t.ServiceName
+		}
+
+		m["network"] = "grpc"
+		m["grpc-opts"] = grpcOpts
+
+	default:
+		log.Errorf("unsupported xray network:%s", s.Network)
+		return false
+	}
+
+	return true
+}
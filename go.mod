@@ -5,10 +5,12 @@ go 1.21
 toolchain go1.21.5
 
 require (
+	github.com/andybalholm/brotli v1.1.0
 	github.com/elliotchance/pie/v2 v2.8.0
 	github.com/go-resty/resty/v2 v2.11.0
 	github.com/ice-cream-heaven/log v0.0.0-20230715032903-f1d27cf7b685
 	github.com/ice-cream-heaven/utils v0.0.0-20240112084616-4f0af3fbac1f
+	github.com/klauspost/compress v1.17.6
 	github.com/metacubex/mihomo v1.18.0
 	golang.org/x/exp v0.0.0-20240205201215-2c58cdc269a3
 	gopkg.in/yaml.v3 v3.0.1
@@ -19,7 +21,6 @@ require (
 	github.com/RyuaNerin/go-krypto v1.2.4 // indirect
 	github.com/Yawning/aez v0.0.0-20211027044916-e49e68abd344 // indirect
 	github.com/aead/chacha20 v0.0.0-20180709150244-8b13a72661da // indirect
-	github.com/andybalholm/brotli v1.1.0 // indirect
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
 	github.com/bytedance/sonic v1.10.2 // indirect
@@ -47,7 +48,6 @@ require (
 	github.com/insomniacslk/dhcp v0.0.0-20240204152450-ca2dc33955c1 // indirect
 	github.com/josharian/native v1.1.0 // indirect
 	github.com/jpillora/backoff v1.0.0 // indirect
-	github.com/klauspost/compress v1.17.6 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.6 // indirect
 	github.com/lestrrat-go/file-rotatelogs v2.4.0+incompatible // indirect
 	github.com/lestrrat-go/strftime v1.0.6 // indirect